@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/queries"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader carries a static api key.
+const APIKeyHeader = "X-Api-Key"
+
+// APIKeys authenticates the keys stored hashed in a table having "name", "key_hash" and "roles" (space separated) columns.
+// A soft deleted key is revoked.
+type APIKeys struct {
+	db    *gorm.DB
+	table string
+}
+
+func NewAPIKeys(db *gorm.DB, table string) *APIKeys {
+	return &APIKeys{db: db, table: table}
+}
+
+func (a *APIKeys) Authenticate(ctx *gin.Context) (*Principal, error) {
+	key := ctx.GetHeader(APIKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	value := map[string]any{}
+	query := a.db.WithContext(ctx.Request.Context()).Table(a.table).Where(a.table+".key_hash = ?", HashKey(key))
+
+	if err := queries.WithoutTrashed(query, a.table).Take(&value).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: unknown or revoked api key", ErrInvalidCredentials)
+		}
+		return nil, err
+	}
+
+	return &Principal{Subject: text(value["name"]), Roles: strings.Fields(text(value["roles"])), Method: "api_key"}, nil
+}
+
+// Create stores a new key and returns it, only its hash is kept so it can't be shown again.
+func (a *APIKeys) Create(name string, roles []string) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	key := hex.EncodeToString(random)
+
+	err := a.db.Table(a.table).Create(map[string]any{
+		"name":     name,
+		"key_hash": HashKey(key),
+		"roles":    strings.Join(roles, " "),
+	}).Error
+
+	return key, err
+}
+
+// HashKey returns the hex encoded sha256 of a key, as stored in the key_hash column.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func text(value any) string {
+	if value == nil {
+		return ""
+	}
+
+	return queries.Key(value)
+}
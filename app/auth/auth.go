@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/config"
+)
+
+// FromConfig builds the authenticators enabled by the configuration, none means authentication is disabled.
+func FromConfig(cfg config.Config) ([]Authenticator, error) {
+	authenticators := []Authenticator{}
+
+	if cfg.AuthAdminRole != "" {
+		AdminRole = cfg.AuthAdminRole
+	}
+
+	if cfg.AuthJWTSecret != "" || cfg.AuthJWKSFile != "" {
+		jwt, err := NewJWT(cfg.AuthJWTSecret, cfg.AuthJWKSFile, cfg.AuthJWTIssuer, cfg.AuthJWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwt)
+	}
+
+	if cfg.AuthAPIKeyTable != "" {
+		authenticators = append(authenticators, NewAPIKeys(database.Default(), cfg.AuthAPIKeyTable))
+	}
+
+	return authenticators, nil
+}
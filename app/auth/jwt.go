@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWT authenticates "Authorization: Bearer <token>" requests signed with HS256 or RS256.
+type JWT struct {
+	secret   []byte
+	keys     map[string]*rsa.PublicKey
+	methods  []string
+	issuer   string
+	audience string
+}
+
+// NewJWT verifies HS256 tokens against secret and RS256 tokens against the keys of a JWKS file, either may be empty.
+func NewJWT(secret string, jwksFile string, issuer string, audience string) (*JWT, error) {
+	a := &JWT{keys: map[string]*rsa.PublicKey{}, issuer: issuer, audience: audience}
+
+	if secret != "" {
+		a.secret = []byte(secret)
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if jwksFile != "" {
+		keys, err := readJWKS(jwksFile)
+		if err != nil {
+			return nil, err
+		}
+
+		a.keys = keys
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(a.methods) == 0 {
+		return nil, errors.New("jwt authentication needs a secret or a jwks file")
+	}
+
+	return a, nil
+}
+
+func (a *JWT) Authenticate(ctx *gin.Context) (*Principal, error) {
+	scheme, token, _ := strings.Cut(ctx.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(a.methods)}
+	if a.issuer != "" {
+		options = append(options, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		options = append(options, jwt.WithAudience(a.audience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, a.key, options...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	if exp, err := claims.GetExpirationTime(); err != nil || exp == nil {
+		return nil, fmt.Errorf("%w: token has no expiration time", ErrInvalidCredentials)
+	}
+
+	// the subject identifies the principal, eg: as the owner of its rows
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &Principal{Subject: subject, Roles: rolesOf(claims), Method: "jwt", Claims: claims}, nil
+}
+
+// key picks the verification key matching the token algorithm and key id.
+func (a *JWT) key(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return a.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+
+	// a single key without "kid" is used for every token
+	if key, ok := a.keys[""]; ok && len(a.keys) == 1 {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// rolesOf reads the "roles" claim, either an array or a space separated string, or the single "role" claim.
+func rolesOf(claims jwt.MapClaims) []string {
+	roles := []string{}
+
+	switch value := claims["roles"].(type) {
+	case []any:
+		for _, role := range value {
+			if s, ok := role.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	case string:
+		roles = append(roles, strings.Fields(value)...)
+	}
+
+	if role, ok := claims["role"].(string); ok && role != "" {
+		roles = append(roles, role)
+	}
+
+	return roles
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// readJWKS loads the RSA signing keys of a JWKS file, indexed by key id.
+func readJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", path, key.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", path, key.Kid, err)
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no RSA signing key found", path)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"github.com/62teknologi/62whale/app/transformers"
+)
+
+// Role values of a policy not bound to a granted role.
+const (
+	Anyone        = "*"
+	Authenticated = "authenticated"
+)
+
+// Policy maps roles to the operations of a catalog and to the fields they may write, read from request/<table>/policy.json.
+type Policy struct {
+	Operations map[string][]string
+	Fields     map[string][]string
+}
+
+// PolicyOf returns the policy declared for table, false when the table has none and everything is allowed.
+func PolicyOf(table string) (*Policy, bool) {
+	declared, err := transformers.Get(transformers.PolicyName(table))
+	if err != nil {
+		return nil, false
+	}
+
+	operations, _ := declared["operations"].(map[string]any)
+	fields, _ := declared["fields"].(map[string]any)
+
+	return &Policy{Operations: roleLists(operations), Fields: roleLists(fields)}, true
+}
+
+// Allows reports whether the principal, nil when anonymous, may run the operation.
+// Roles are looked up by operation, then by its "read" or "write" group, then under "*", an operation not listed is denied.
+func (p *Policy) Allows(principal *Principal, operation string) bool {
+	if principal != nil && principal.IsAdmin() {
+		return true
+	}
+
+	group := "write"
+	if transformers.ReadOperations[operation] {
+		group = "read"
+	}
+
+	for _, key := range []string{operation, group, Anyone} {
+		if roles, ok := p.Operations[key]; ok {
+			return granted(principal, roles)
+		}
+	}
+
+	return false
+}
+
+// CanWrite reports whether the principal may send the field, a field not listed is writable by anyone allowed to write.
+func (p *Policy) CanWrite(principal *Principal, field string) bool {
+	if principal != nil && principal.IsAdmin() {
+		return true
+	}
+
+	roles, ok := p.Fields[field]
+
+	return !ok || granted(principal, roles)
+}
+
+func granted(principal *Principal, roles []string) bool {
+	for _, role := range roles {
+		switch {
+		case role == Anyone:
+			return true
+		case principal == nil:
+			continue
+		case role == Authenticated || principal.HasRole(role):
+			return true
+		}
+	}
+
+	return false
+}
+
+func roleLists(value map[string]any) map[string][]string {
+	lists := map[string][]string{}
+
+	for key, roles := range value {
+		list, _ := roles.([]any)
+		for _, role := range list {
+			if name, ok := role.(string); ok {
+				lists[key] = append(lists[key], name)
+			}
+		}
+
+		if _, ok := lists[key]; !ok {
+			lists[key] = []string{}
+		}
+	}
+
+	return lists
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKey stores the authenticated principal in the gin context.
+const ContextKey = "principal"
+
+// AdminRole is the role bypassing ownership checks.
+var AdminRole = "admin"
+
+// ErrNoCredentials is returned by an authenticator when the request doesn't carry its kind of credentials.
+var ErrNoCredentials = errors.New("no credentials")
+
+// ErrInvalidCredentials wraps the errors of credentials that are refused, any other error is a failure of the authenticator.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Principal is the caller of an authenticated request.
+type Principal struct {
+	Subject string
+	Roles   []string
+	// Method is either "jwt" or "api_key"
+	Method string
+	Claims map[string]any
+}
+
+// HasRole reports whether the principal was granted the role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAdmin reports whether the principal bypasses ownership checks.
+func (p *Principal) IsAdmin() bool {
+	return p.HasRole(AdminRole)
+}
+
+// OwnerID is the value stored in owner columns, numeric subjects are kept as numbers.
+func (p *Principal) OwnerID() any {
+	if id, err := strconv.ParseInt(p.Subject, 10, 64); err == nil {
+		return id
+	}
+
+	return p.Subject
+}
+
+// Authenticator resolves the principal of a request, refused credentials are reported with ErrInvalidCredentials.
+type Authenticator interface {
+	Authenticate(ctx *gin.Context) (*Principal, error)
+}
+
+// FromContext returns the principal set by the authentication middleware.
+func FromContext(ctx *gin.Context) (*Principal, bool) {
+	value, ok := ctx.Get(ContextKey)
+	if !ok {
+		return nil, false
+	}
+
+	principal, ok := value.(*Principal)
+
+	return principal, ok
+}
@@ -0,0 +1,136 @@
+package catalogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/transformers"
+)
+
+// ContextKey holds the table of the catalog served by the request.
+const ContextKey = "catalog"
+
+// Catalog is a table exposed through the api, eg: "products" or its sub-resource "product_comments".
+type Catalog struct {
+	// Disable lists the operations refused for the catalog.
+	Disable []string `json:"disable"`
+}
+
+var (
+	mu sync.RWMutex
+	// configured is nil when the catalogs are derived from the transformer tree.
+	configured map[string]Catalog
+)
+
+// Load reads the allow-list file, when it doesn't exist every table having a transformer is exposed.
+func Load(path string) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		mu.Lock()
+		configured = nil
+		mu.Unlock()
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	catalogs := map[string]Catalog{}
+	if err := json.Unmarshal(content, &catalogs); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for table, catalog := range catalogs {
+		for _, operation := range catalog.Disable {
+			if !transformers.IsOperation(operation) {
+				return fmt.Errorf("%s: unknown operation %q for catalog %s, expected one of %s", path, operation, table, strings.Join(transformers.Operations, ", "))
+			}
+		}
+	}
+
+	mu.Lock()
+	configured = catalogs
+	mu.Unlock()
+
+	return nil
+}
+
+// TableOf resolves the table addressed by a route kind and its :table parameter,
+// eg: "product_comments" for /comment/products.
+func TableOf(kind string, param string) string {
+	if kind == "catalog" {
+		return utils.Pluralize.Plural(param)
+	}
+
+	return utils.Pluralize.Singular(param) + "_" + utils.Pluralize.Plural(kind)
+}
+
+// Exists reports whether the table is exposed.
+func Exists(table string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if configured != nil {
+		_, ok := configured[table]
+		return ok
+	}
+
+	for _, name := range transformers.Names() {
+		if parts := strings.Split(name, "/"); len(parts) == 3 && parts[1] == table {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allowed reports whether the operation is enabled on an exposed table.
+func Allowed(table string, operation string) bool {
+	if !Exists(table) {
+		return false
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, disabled := range configured[table].Disable {
+		if disabled == operation {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Names returns the exposed tables in a stable order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	tables := []string{}
+
+	if configured != nil {
+		for table := range configured {
+			tables = append(tables, table)
+		}
+	} else {
+		seen := map[string]bool{}
+		for _, name := range transformers.Names() {
+			parts := strings.Split(name, "/")
+			if len(parts) == 3 && !seen[parts[1]] {
+				seen[parts[1]] = true
+				tables = append(tables, parts[1])
+			}
+		}
+	}
+
+	sort.Strings(tables)
+
+	return tables
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/auth"
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/config"
+)
+
+const apiKeyUsage = `usage: apikey create <name> [--roles "admin editor"]
+
+the key is printed once, only its hash is stored in AUTH_API_KEY_TABLE`
+
+// APIKey creates api keys, it returns the process exit code.
+func APIKey(args []string) int {
+	if len(args) < 2 || args[0] != "create" {
+		fmt.Println(apiKeyUsage)
+		return 1
+	}
+
+	flags := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	roles := flags.String("roles", "", "space separated roles granted to the key")
+	flags.Parse(args[2:])
+
+	if config.Data.AuthAPIKeyTable == "" {
+		fmt.Println("AUTH_API_KEY_TABLE is not configured")
+		return 1
+	}
+
+	if err := database.Connect(config.Data.DBDriver, config.Data.DBSources, config.Data.DBDefaultSource); err != nil {
+		fmt.Println("cannot connect to database: " + err.Error())
+		return 1
+	}
+
+	key, err := auth.NewAPIKeys(database.Default(), config.Data.AuthAPIKeyTable).Create(args[1], strings.Fields(*roles))
+	if err != nil {
+		fmt.Println("cannot create api key: " + err.Error())
+		return 1
+	}
+
+	fmt.Println(key)
+
+	return 0
+}
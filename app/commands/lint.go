@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/transformers"
+	"github.com/62teknologi/62whale/config"
+)
+
+// Lint validates the transformer tree, it returns the process exit code.
+func Lint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	noDB := flags.Bool("no-db", false, "skip checking tables and columns against the database")
+	source := flags.String("db", "", "data source used to check tables and columns, default to DB_DEFAULT_SOURCE")
+	flags.Parse(args)
+
+	dir := config.Data.SettingPath + "/transformers"
+	problems := []transformers.Problem{}
+
+	if *noDB {
+		problems = transformers.Lint(dir, nil)
+	} else {
+		if err := database.Connect(config.Data.DBDriver, config.Data.DBSources, config.Data.DBDefaultSource); err != nil {
+			fmt.Println("cannot connect to database: " + err.Error())
+			return 1
+		}
+
+		db := database.Default()
+		if *source != "" {
+			selected, ok := database.Get(*source)
+			if !ok {
+				fmt.Println("data source " + *source + " is not configured")
+				return 1
+			}
+			db = selected
+		}
+
+		problems = transformers.Lint(dir, db)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem.String())
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%d problem(s) found\n", len(problems))
+		return 1
+	}
+
+	fmt.Println("no problem found")
+
+	return 0
+}
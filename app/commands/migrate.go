@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/migrations"
+	"github.com/62teknologi/62whale/app/transformers"
+	"github.com/62teknologi/62whale/config"
+
+	"github.com/iancoleman/strcase"
+)
+
+const migrateUsage = `usage: migrate <command> [options]
+
+commands:
+  diff                 print the statements needed to match the transformers
+  generate [name]      write a new migration from the diff
+  up                   apply every pending migration
+  down [--steps n]     roll back the latest applied migrations
+  status               list migrations and whether they are applied`
+
+// Migrate derives, writes, applies and rolls back migrations, it returns the process exit code.
+func Migrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		return 1
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	source := flags.String("db", "", "data source to migrate, default to DB_DEFAULT_SOURCE")
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	flags.Parse(args[1:])
+
+	if err := database.Connect(config.Data.DBDriver, config.Data.DBSources, config.Data.DBDefaultSource); err != nil {
+		fmt.Println("cannot connect to database: " + err.Error())
+		return 1
+	}
+
+	db := database.Default()
+	if *source != "" {
+		selected, ok := database.Get(*source)
+		if !ok {
+			fmt.Println("data source " + *source + " is not configured")
+			return 1
+		}
+		db = selected
+	}
+
+	dir := config.Data.MigrationPath
+
+	switch args[0] {
+	case "diff", "generate":
+		dialect, err := migrations.NewDialect(config.Data.DBDriver)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		if err := transformers.Load(config.Data.SettingPath + "/transformers"); err != nil {
+			fmt.Println("cannot load transformers: " + err.Error())
+			return 1
+		}
+
+		if args[0] == "diff" {
+			up, _, err := migrations.Diff(db, dialect, migrations.Derive())
+			if err != nil {
+				fmt.Println(err.Error())
+				return 1
+			}
+
+			for _, statement := range up {
+				fmt.Println(statement + ";")
+			}
+
+			return 0
+		}
+
+		name := "schema"
+		if flags.NArg() > 0 {
+			name = strcase.ToSnake(strings.Join(flags.Args(), "_"))
+		}
+
+		migration, created, err := migrations.Generate(db, dialect, dir, name)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		if !created {
+			fmt.Println("database already matches the transformers")
+			return 0
+		}
+
+		fmt.Println("created migration " + migration.Version + "_" + migration.Name)
+	case "up":
+		done, err := migrations.Up(db, dir)
+		for _, migration := range done {
+			fmt.Println("applied " + migration.Version + "_" + migration.Name)
+		}
+
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	case "down":
+		done, err := migrations.Down(db, dir, *steps)
+		for _, migration := range done {
+			fmt.Println("rolled back " + migration.Version + "_" + migration.Name)
+		}
+
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+	case "status":
+		list, err := migrations.List(db, dir)
+		if err != nil {
+			fmt.Println(err.Error())
+			return 1
+		}
+
+		for _, migration := range list {
+			status := "pending"
+			if migration.Applied {
+				status = "applied"
+			}
+			fmt.Println(status + "  " + migration.Version + "_" + migration.Name)
+		}
+	default:
+		fmt.Println(migrateUsage)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// ContextKey is the gin context key holding the connection selected for the request.
+const ContextKey = "db"
+
+// memoryDSN selects an in-memory sqlite database.
+const memoryDSN = ":memory:"
+
+var connections = map[string]*gorm.DB{}
+var defaultSource string
+
+// Connect opens one connection pool per named data source.
+func Connect(driver string, sources map[string]string, defaultName string) error {
+	if _, ok := sources[defaultName]; !ok {
+		return fmt.Errorf("default data source %q is not configured", defaultName)
+	}
+
+	for name, dsn := range sources {
+		if driver == "sqlite" && dsn == memoryDSN {
+			dsn = sharedMemoryDSN(name)
+		}
+
+		dialector, err := Dialector(driver, dsn)
+		if err != nil {
+			return err
+		}
+
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		if err != nil {
+			return fmt.Errorf("cannot connect to data source %q: %w", name, err)
+		}
+
+		connections[name] = db
+	}
+
+	defaultSource = defaultName
+
+	return nil
+}
+
+// Close closes the connection pool of every data source, the first failure is returned once all were tried.
+func Close() error {
+	var closeErr error
+
+	for name, db := range connections {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+
+		if err != nil && closeErr == nil {
+			closeErr = fmt.Errorf("cannot close data source %q: %w", name, err)
+		}
+	}
+
+	connections = map[string]*gorm.DB{}
+
+	return closeErr
+}
+
+// Dialector returns the gorm dialector for the given driver name.
+func Dialector(driver string, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "mysql":
+		return mysql.Open(dsn), nil
+	case "postgres":
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		return sqlite.Open(dsn), nil
+	}
+
+	return nil, fmt.Errorf("unsupported database driver %q", driver)
+}
+
+// sharedMemoryDSN names the in-memory database of a data source so every connection of its pool sees the same
+// database, a plain ":memory:" would give each connection its own empty one.
+func sharedMemoryDSN(name string) string {
+	return "file:whale_" + name + "?mode=memory&cache=shared"
+}
+
+// Get returns the connection of the named data source.
+func Get(name string) (*gorm.DB, bool) {
+	db, ok := connections[name]
+	return db, ok
+}
+
+// Default returns the connection of the default data source.
+func Default() *gorm.DB {
+	return connections[defaultSource]
+}
+
+// DefaultName returns the name of the default data source.
+func DefaultName() string {
+	return defaultSource
+}
+
+// Names returns the configured data source names in a stable order.
+func Names() []string {
+	names := make([]string, 0, len(connections))
+	for name := range connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FromContext returns the connection selected for the current request,
+// falling back to the default data source.
+func FromContext(ctx *gin.Context) *gorm.DB {
+	db := Default()
+
+	if value, ok := ctx.Get(ContextKey); ok {
+		if selected, ok := value.(*gorm.DB); ok {
+			db = selected
+		}
+	}
+
+	return db.WithContext(ctx.Request.Context())
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// RecursiveCTE reports whether the server of db runs recursive common table expressions. It is false only when the
+// server is known not to: mysql before 8.0 and mariadb before 10.2.2.
+func RecursiveCTE(db *gorm.DB) bool {
+	dialector, ok := db.Dialector.(*mysql.Dialector)
+	if !ok || dialector.ServerVersion == "" {
+		return true
+	}
+
+	version := dialector.ServerVersion
+	if strings.Contains(version, "MariaDB") {
+		// eg: "5.5.5-10.1.48-MariaDB", the prefix is only sent for the replication protocol
+		return atLeast(strings.TrimPrefix(version, "5.5.5-"), 10, 2, 2)
+	}
+
+	return atLeast(version, 8)
+}
+
+// atLeast compares the leading numbers of a version, eg: "5.7.42-log", with minimum. An unreadable version passes.
+func atLeast(version string, minimum ...int) bool {
+	parts := strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' })
+
+	for i, expected := range minimum {
+		if i >= len(parts) {
+			return true
+		}
+
+		number, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return true
+		}
+
+		if number != expected {
+			return number > expected
+		}
+	}
+
+	return true
+}
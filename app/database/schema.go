@@ -0,0 +1,37 @@
+package database
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type columnKey struct {
+	dialector gorm.Dialector
+	table     string
+	column    string
+}
+
+var columns sync.Map
+
+// HasColumn reports whether table has the column, the answer is cached per connection until ResetColumns.
+func HasColumn(db *gorm.DB, table string, column string) bool {
+	key := columnKey{dialector: db.Dialector, table: table, column: column}
+
+	if exists, ok := columns.Load(key); ok {
+		return exists.(bool)
+	}
+
+	exists := db.Session(&gorm.Session{NewDB: true}).Migrator().HasColumn(table, column)
+	columns.Store(key, exists)
+
+	return exists
+}
+
+// ResetColumns forgets the cached columns, eg: once the schema is migrated or the transformers are reloaded.
+func ResetColumns() {
+	columns.Range(func(key, _ any) bool {
+		columns.Delete(key)
+		return true
+	})
+}
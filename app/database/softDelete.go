@@ -0,0 +1,29 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeletedAt is the column marking a row as soft deleted.
+const DeletedAt = "deleted_at"
+
+// SoftDeletes reports whether rows of table are soft deleted.
+func SoftDeletes(db *gorm.DB, table string) bool {
+	return HasColumn(db, table, DeletedAt)
+}
+
+// Delete soft deletes the rows matched by query when the table supports it, as a new revision, otherwise removes them.
+func Delete(query *gorm.DB, table string) *gorm.DB {
+	if SoftDeletes(query, table) {
+		return query.Where(table + "." + DeletedAt + " IS NULL").Updates(Touch(query, table, map[string]any{DeletedAt: time.Now()}))
+	}
+
+	return query.Delete(map[string]any{})
+}
+
+// Restore brings back the soft deleted rows matched by query.
+func Restore(query *gorm.DB, table string) *gorm.DB {
+	return query.Where(table + "." + DeletedAt + " IS NOT NULL").Updates(Touch(query, table, map[string]any{DeletedAt: nil}))
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Version is the optional column counting the revisions of a row.
+const Version = "version"
+
+// UpdatedAt is the column holding the last modification time of a row.
+const UpdatedAt = "updated_at"
+
+// Versioned reports whether rows of table carry a version column.
+func Versioned(db *gorm.DB, table string) bool {
+	return HasColumn(db, table, Version)
+}
+
+// RevisionColumns returns the columns of table an ETag is built from.
+func RevisionColumns(db *gorm.DB, table string) []string {
+	columns := []string{}
+	for _, column := range []string{Version, UpdatedAt} {
+		if HasColumn(db, table, column) {
+			columns = append(columns, column)
+		}
+	}
+
+	return columns
+}
+
+// Touch returns the values of an update completed with the version increment and the modification time.
+func Touch(db *gorm.DB, table string, values map[string]any) map[string]any {
+	touched := make(map[string]any, len(values)+2)
+	for key, value := range values {
+		touched[key] = value
+	}
+
+	if Versioned(db, table) {
+		touched[Version] = gorm.Expr(Version + " + 1")
+	}
+
+	if HasColumn(db, table, UpdatedAt) {
+		touched[UpdatedAt] = time.Now()
+	}
+
+	return touched
+}
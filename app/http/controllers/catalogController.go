@@ -3,7 +3,9 @@ package controllers
 import (
 	"fmt"
 	"github.com/62teknologi/62whale/62golib/utils"
-	"github.com/62teknologi/62whale/config"
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/queries"
+	"github.com/62teknologi/62whale/app/transformers"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gosimple/slug"
@@ -13,6 +15,21 @@ import (
 	"time"
 )
 
+const (
+	bulkAtomic  = "atomic"
+	bulkPartial = "partial"
+	// maximum number of records accepted by a bulk request
+	bulkLimit = 1000
+)
+
+// bulkResult reports the outcome of a single bulk row.
+type bulkResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Errors any    `json:"errors,omitempty"`
+	ID     any    `json:"id,omitempty"`
+}
+
 type CatalogController struct {
 	SingularName  string
 	PluralName    string
@@ -34,14 +51,25 @@ func (ctrl CatalogController) Find(ctx *gin.Context) {
 
 	value := map[string]any{}
 	columns := []string{ctrl.PluralName + ".*"}
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/response/" + ctrl.PluralName + "/find.json")
+	transformer, err := transformers.Get("response/" + ctrl.PluralName + "/find")
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	query := utils.DB.Table(ctrl.PluralName)
+	query := database.FromContext(ctx).Table(ctrl.PluralName)
+	queries.SetTrashedByQuery(query, ctrl.PluralName, ctx)
+	if err := queries.SetIncludes(ctx, transformer); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if err := queries.SetFields(ctx, database.FromContext(ctx), ctrl.Table, transformer, &columns); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
 	utils.SetBelongsTo(query, transformer, &columns, ctx)
 	delete(transformer, "filterable")
 	field := "id"
@@ -59,8 +87,17 @@ func (ctrl CatalogController) Find(ctx *gin.Context) {
 
 	utils.MapValuesShifter(transformer, value)
 	utils.AttachBelongsTo(transformer, value)
-	utils.AttachHasMany(transformer)
-	utils.AttachManyToMany(transformer)
+	if err := queries.AttachHasMany(database.FromContext(ctx), transformer); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if err := queries.AttachManyToMany(database.FromContext(ctx), transformer); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	setETag(ctx, value)
 
 	ctx.JSON(http.StatusOK, utils.ResponseData("success", "find "+ctrl.SingularLabel+" success", transformer))
 }
@@ -70,35 +107,82 @@ func (ctrl CatalogController) FindAll(ctx *gin.Context) {
 
 	values := []map[string]any{}
 	columns := []string{ctrl.PluralName + ".*"}
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/response/" + ctrl.PluralName + "/find.json")
+	transformer, err := transformers.Get("response/" + ctrl.PluralName + "/find")
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	query := utils.DB.Table(ctrl.Table)
-	filter := utils.SetFilterByQuery(query, transformer, ctx)
+	query := database.FromContext(ctx).Table(ctrl.Table)
+	queries.SetTrashedByQuery(query, ctrl.Table, ctx)
+	filter, err := queries.SetFilterByQuery(query, ctrl.Table, transformer, ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
 	search := utils.SetGlobalSearch(query, transformer, ctx)
 
-	utils.SetOrderByQuery(query, ctx)
+	cursor, err := queries.NewCursor(ctx, database.FromContext(ctx), ctrl.Table)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if cursor == nil {
+		if err := queries.SetOrderByQuery(query, ctrl.Table, transformer, ctx); err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+			return
+		}
+	}
+
+	if err := queries.SetIncludes(ctx, transformer); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if err := queries.SetFields(ctx, database.FromContext(ctx), ctrl.Table, transformer, &columns); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
 	utils.SetBelongsTo(query, transformer, &columns, ctx)
 
 	delete(transformer, "filterable")
 	delete(transformer, "searchable")
 
-	pagination := utils.SetPagination(query, ctx)
+	var pagination map[string]any
+	if cursor != nil {
+		if pagination, err = cursor.Apply(query, &columns); err != nil {
+			ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+			return
+		}
+	} else {
+		pagination = utils.SetPagination(query, ctx)
+	}
 
 	if err := query.Select(columns).Find(&values).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
+	if cursor != nil {
+		values = cursor.Page(values, pagination)
+	}
+
 	customResponses := utils.MultiMapValuesShifter(transformer, values)
 	summary := utils.GetSummary(transformer, values)
 
-	utils.MultiAttachHasMany(customResponses, ctx)
-	utils.MultiAttachManyToMany(customResponses, ctx)
+	if err := queries.MultiAttachHasMany(database.FromContext(ctx), customResponses); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if err := queries.MultiAttachManyToMany(database.FromContext(ctx), customResponses); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
 
 	ctx.JSON(http.StatusOK, utils.ResponseDataPaginate("success", "find "+ctrl.PluralLabel+" success", customResponses, pagination, filter, search, summary))
 }
@@ -106,7 +190,7 @@ func (ctrl CatalogController) FindAll(ctx *gin.Context) {
 func (ctrl CatalogController) Create(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/request/" + ctrl.PluralName + "/create.json")
+	transformer, err := transformers.Get("request/" + ctrl.PluralName + "/create")
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
@@ -114,14 +198,31 @@ func (ctrl CatalogController) Create(ctx *gin.Context) {
 	}
 
 	input := utils.ParseForm(ctx)
+	fillOwner(ctx, ctrl.PluralName, input)
 
 	if validation, err := utils.Validate(input, transformer); err {
 		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", validation.Errors))
 		return
 	}
 
+	if err = database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return ctrl.create(ctx, tx, transformer, input)
+	}); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "create "+ctrl.SingularLabel+" success", transformer))
+}
+
+// create inserts a validated record with its has_many and many_to_many relations,
+// the transformer is filled with the created values.
+func (ctrl CatalogController) create(ctx *gin.Context, tx *gorm.DB, transformer map[string]any, input map[string]any) error {
+	var err error
+
 	utils.MapValuesShifter(transformer, input)
 	utils.MapNullValuesRemover(transformer)
+	fillOwner(ctx, ctrl.PluralName, transformer)
 
 	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
 	if input["name"] != nil {
@@ -131,301 +232,511 @@ func (ctrl CatalogController) Create(ctx *gin.Context) {
 		transformer["slug"] = uuid.New()
 	}
 
-	if err = utils.DB.Transaction(func(tx *gorm.DB) error {
-		if _, ok := transformer["duplicate"]; ok {
-			for i := range transformer["has_many"].(map[string]any) {
-				transformerValues := transformer[i]
-				defaultItem := utils.FilterMap(transformerValues, func(item map[string]any) bool {
-					_, itemDefaultExist := item["default"]
-					if itemDefaultExist {
-						isDefaultItem := item["default"].(bool)
-						return isDefaultItem == true
-					}
-					return false
-				})
-
-				if len(defaultItem) != 0 {
-					utils.SetDoubleRecord(transformer, defaultItem[0], i)
-				} else {
-					utils.SetDoubleRecord(transformer, transformerValues.([]any)[0].(map[string]any), i)
+	if _, ok := transformer["duplicate"]; ok {
+		for i := range transformer["has_many"].(map[string]any) {
+			transformerValues := transformer[i]
+			defaultItem := utils.FilterMap(transformerValues, func(item map[string]any) bool {
+				_, itemDefaultExist := item["default"]
+				if itemDefaultExist {
+					isDefaultItem := item["default"].(bool)
+					return isDefaultItem == true
 				}
+				return false
+			})
+
+			if len(defaultItem) != 0 {
+				utils.SetDoubleRecord(transformer, defaultItem[0], i)
+			} else {
+				utils.SetDoubleRecord(transformer, transformerValues.([]any)[0].(map[string]any), i)
 			}
 		}
+	}
 
-		hasManyItems := make(map[string]any)
+	hasManyItems := make(map[string]any)
 
-		if transformer["has_many"] != nil {
-			for i := range transformer["has_many"].(map[string]any) {
-				hasManyItems[i] = transformer[i]
-				delete(transformer, i)
-			}
+	if transformer["has_many"] != nil {
+		for i := range transformer["has_many"].(map[string]any) {
+			hasManyItems[i] = transformer[i]
+			delete(transformer, i)
 		}
+	}
 
-		hasManyToManyGroups := make(map[string]any)
+	hasManyToManyGroups := make(map[string]any)
 
-		if transformer["many_to_many"] != nil {
-			for i := range transformer["many_to_many"].(map[string]any) {
-				hasManyToManyGroups[i] = transformer[i]
-				delete(transformer, i)
-			}
+	if transformer["many_to_many"] != nil {
+		for i := range transformer["many_to_many"].(map[string]any) {
+			hasManyToManyGroups[i] = transformer[i]
+			delete(transformer, i)
 		}
+	}
 
-		createdProduct := make(map[string]any)
+	createdProduct := make(map[string]any)
 
-		for k, v := range transformer {
-			createdProduct[k] = v
-		}
+	for k, v := range transformer {
+		createdProduct[k] = v
+	}
 
-		createdProduct = utils.RemoveSliceAndMap(createdProduct)
+	createdProduct = utils.RemoveSliceAndMap(createdProduct)
 
-		if err = tx.Table(ctrl.PluralName).Create(&createdProduct).Error; err != nil {
+	if err = tx.Table(ctrl.PluralName).Create(&createdProduct).Error; err != nil {
+		return err
+	}
+
+	if createdProduct["id"] == nil {
+		var created map[string]any
+		if err = tx.Table(ctrl.PluralName).Where(createdProduct).Order("id desc").Take(&created).Error; err != nil {
 			return err
 		}
+		createdProduct["id"] = created["id"]
+	}
 
-		var processHasManyError error
-		utils.ProcessHasMany(transformer, func(key string, data map[string]any, options map[string]any, parentKey string) {
-			if processHasManyError != nil {
-				return
-			}
+	transformer["id"] = createdProduct["id"]
 
-			var parentData map[string]any
-			var items []map[string]any
-			if options["ft"].(string) == ctrl.PluralName {
-				tx.Table(options["ft"].(string)).Where(createdProduct).Take(&parentData)
+	var processHasManyError error
+	utils.ProcessHasMany(transformer, func(key string, data map[string]any, options map[string]any, parentKey string) {
+		if processHasManyError != nil {
+			return
+		}
 
-				items = utils.Prepare1toM(options["fk"].(string), parentData["id"], hasManyItems[key].([]any))
-				for i := range items {
-					items[i] = utils.RemoveSliceAndMap(items[i])
-				}
+		var parentData map[string]any
+		var items []map[string]any
+		if options["ft"].(string) == ctrl.PluralName {
+			tx.Table(options["ft"].(string)).Where(createdProduct).Take(&parentData)
 
-				if err = tx.Table(options["table"].(string)).Create(&items).Error; err != nil {
-					processHasManyError = fmt.Errorf("error while create %v: %e", key, err.Error())
-					return
-				}
+			items = utils.Prepare1toM(options["fk"].(string), parentData["id"], hasManyItems[key].([]any))
+			for i := range items {
+				items[i] = utils.RemoveSliceAndMap(items[i])
+			}
 
-				transformer[key] = items
-			} else {
-				for i, v := range hasManyItems[parentKey].([]any) {
-					if _, ok := v.(map[string]any)[key]; ok {
-						if err = tx.Table(options["ft"].(string)).Where(utils.RemoveSliceAndMap(v.(map[string]any))).Take(&parentData).Error; err != nil {
-							processHasManyError = fmt.Errorf("error while create %v: %e", key, err.Error())
-							return
-						}
-						items = utils.Prepare1toM(options["fk"].(string), parentData["id"], v.(map[string]any)[key])
-						for i := range items {
-							items[i] = utils.RemoveSliceAndMap(items[i])
-						}
-
-						if err = tx.Table(options["table"].(string)).Create(&items).Error; err != nil {
-							processHasManyError = fmt.Errorf("error while create %v: %e", key, err.Error())
-							return
-						}
-
-						transformer[parentKey].([]map[string]any)[i][key] = items
+			if err = tx.Table(options["table"].(string)).Create(&items).Error; err != nil {
+				processHasManyError = fmt.Errorf("error while create %v: %w", key, err)
+				return
+			}
+
+			transformer[key] = items
+		} else {
+			for i, v := range hasManyItems[parentKey].([]any) {
+				if _, ok := v.(map[string]any)[key]; ok {
+					if err = tx.Table(options["ft"].(string)).Where(utils.RemoveSliceAndMap(v.(map[string]any))).Take(&parentData).Error; err != nil {
+						processHasManyError = fmt.Errorf("error while create %v: %w", key, err)
+						return
+					}
+					items = utils.Prepare1toM(options["fk"].(string), parentData["id"], v.(map[string]any)[key])
+					for i := range items {
+						items[i] = utils.RemoveSliceAndMap(items[i])
 					}
+
+					if err = tx.Table(options["table"].(string)).Create(&items).Error; err != nil {
+						processHasManyError = fmt.Errorf("error while create %v: %w", key, err)
+						return
+					}
+
+					transformer[parentKey].([]map[string]any)[i][key] = items
 				}
 			}
-		}, "")
-
-		if processHasManyError != nil {
-			return processHasManyError
 		}
+	}, "")
 
-		if transformer["many_to_many"] != nil {
-			for i, v := range transformer["many_to_many"].(map[string]any) {
-				var parentData map[string]any
-				tx.Table(ctrl.PluralName).Where(createdProduct).Take(&parentData)
+	if processHasManyError != nil {
+		return processHasManyError
+	}
 
-				table := v.(map[string]any)["table"].(string)
-				fk1 := v.(map[string]any)["fk_1"].(string)
-				fk2 := v.(map[string]any)["fk_2"].(string)
+	if transformer["many_to_many"] != nil {
+		for i, v := range transformer["many_to_many"].(map[string]any) {
+			var parentData map[string]any
+			tx.Table(ctrl.PluralName).Where(createdProduct).Take(&parentData)
 
-				tx.Table(ctrl.PluralName).Where("slug = ?", transformer["slug"]).Take(&transformer)
-				groups := utils.PrepareMtoM(fk1, parentData["id"], fk2, hasManyToManyGroups[i])
+			table := v.(map[string]any)["table"].(string)
+			fk1, fk2 := queries.PivotKeys(v.(map[string]any))
+			if fk1 == "" || fk2 == "" {
+				return fmt.Errorf("many_to_many %v needs fk_1 and fk_2", i)
+			}
 
-				if err = tx.Table(table).Create(&groups).Error; err != nil {
-					return err
-				}
+			tx.Table(ctrl.PluralName).Where("slug = ?", transformer["slug"]).Take(&transformer)
+			groups := utils.PrepareMtoM(fk1, parentData["id"], fk2, hasManyToManyGroups[i])
 
-				transformer[i] = groups
+			if err = tx.Table(table).Create(&groups).Error; err != nil {
+				return err
 			}
-		}
 
-		return nil
-	}); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
-		return
+			transformer[i] = groups
+		}
 	}
 
 	delete(transformer, "has_many")
 	delete(transformer, "many_to_many")
 	delete(transformer, "duplicate")
 
-	ctx.JSON(http.StatusOK, utils.ResponseData("success", "create "+ctrl.SingularLabel+" success", transformer))
+	return nil
 }
 
-func (ctrl CatalogController) Update(ctx *gin.Context) {
+// BulkCreate inserts an array of records, "mode=atomic" (default) creates all of them or none,
+// "mode=partial" keeps the valid rows and reports the failing ones.
+func (ctrl CatalogController) BulkCreate(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/request/" + ctrl.PluralName + "/update.json")
+	transformer, err := transformers.Get("request/" + ctrl.PluralName + "/create")
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	input := utils.ParseForm(ctx)
+	mode := ctx.DefaultQuery("mode", bulkAtomic)
+	if mode != bulkAtomic && mode != bulkPartial {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "mode must be "+bulkAtomic+" or "+bulkPartial, nil))
+		return
+	}
 
-	if validation, err := utils.Validate(input, transformer); err {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", validation.Errors))
+	var inputs []map[string]any
+	if err := ctx.ShouldBindJSON(&inputs); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "body must be an array of "+ctrl.PluralLabel, nil))
 		return
 	}
 
-	// not sure is it needed or not, may confusing if slug changes
-	if input["name"] != nil && transformer["slug"] == "" {
-		name, _ := input["name"].(string)
-		transformer["slug"] = slug.Make(name)
+	if len(inputs) == 0 || len(inputs) > bulkLimit {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "body must contain between 1 and "+strconv.Itoa(bulkLimit)+" "+ctrl.PluralLabel, nil))
+		return
 	}
 
-	utils.MapValuesShifter(transformer, input)
-	utils.MapNullValuesRemover(transformer)
+	results := make([]bulkResult, len(inputs))
+	rows := make([]map[string]any, len(inputs))
+	invalid := 0
 
-	hasMany := transformer["has_many"]
-	delete(transformer, "has_many")
+	for i, input := range inputs {
+		results[i] = bulkResult{Index: i}
+		fillOwner(ctx, ctrl.PluralName, input)
+		rows[i] = transformers.Copy(transformer)
 
-	manyToMany := transformer["many_to_many"]
-	delete(transformer, "many_to_many")
+		if validation, err := utils.Validate(input, rows[i]); err {
+			results[i].Status = "invalid"
+			results[i].Errors = validation.Errors
+			invalid++
+		}
+	}
 
-	_, duplicateExist := transformer["duplicate"]
-
-	if err := utils.DB.Transaction(func(tx *gorm.DB) error {
-		if duplicateExist {
-			for i := range hasMany.(map[string]any) {
-				transformerValues := transformer[i]
-				defaultItem := utils.FilterMap(transformerValues, func(item map[string]any) bool {
-					_, itemDefaultExist := item["default"]
-					if itemDefaultExist {
-						isDefaultItem := item["default"].(bool)
-						return isDefaultItem == true
+	if mode == bulkAtomic && invalid > 0 {
+		for i := range results {
+			if results[i].Status == "" {
+				results[i].Status = "skipped"
+			}
+		}
+
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", results))
+		return
+	}
+
+	failed := invalid
+
+	err = database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, input := range inputs {
+			if results[i].Status != "" {
+				continue
+			}
+
+			if mode == bulkAtomic {
+				if err := ctrl.create(ctx, tx, rows[i], input); err != nil {
+					results[i].Status = "failed"
+					results[i].Errors = err.Error()
+					return err
+				}
+			} else {
+				savepoint := "bulk_" + strconv.Itoa(i)
+				if err := tx.SavePoint(savepoint).Error; err != nil {
+					return err
+				}
+
+				if err := ctrl.create(ctx, tx, rows[i], input); err != nil {
+					// undo the writes of the failing record only, the others are kept
+					if err := tx.RollbackTo(savepoint).Error; err != nil {
+						// the writes of the record can't be undone alone, give up on the whole batch
+						return err
 					}
-					return false
-				})
 
-				if len(defaultItem) != 0 {
-					utils.SetDoubleRecord(transformer, defaultItem[0], i)
-				} else {
-					utils.SetDoubleRecord(transformer, transformerValues.([]any)[0].(map[string]any), i)
+					results[i].Status = "failed"
+					results[i].Errors = err.Error()
+					failed++
+					continue
 				}
-				delete(transformer, "duplicate")
 			}
+
+			results[i].Status = "created"
+			results[i].ID = rows[i]["id"]
 		}
 
-		hasManyItems := make(map[string]any)
-		if hasMany != nil {
-			for i := range hasMany.(map[string]any) {
-				hasManyItems[i] = transformer[i]
-				delete(transformer, i)
+		return nil
+	})
+
+	if err != nil {
+		for i := range results {
+			switch results[i].Status {
+			case "created":
+				results[i].Status = "rolled_back"
+				results[i].ID = nil
+			case "":
+				results[i].Status = "skipped"
 			}
 		}
 
-		hasManyToManyGroups := make(map[string]any)
-		if hasManyToManyGroups != nil {
-			for i := range manyToMany.(map[string]any) {
-				hasManyToManyGroups[i] = transformer[i]
-				delete(transformer, i)
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), results))
+		return
+	}
+
+	if failed > 0 {
+		ctx.JSON(http.StatusMultiStatus, utils.ResponseData("error", strconv.Itoa(failed)+" of "+strconv.Itoa(len(inputs))+" "+ctrl.PluralLabel+" not created", results))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "bulk create "+ctrl.PluralLabel+" success", results))
+}
+
+// Update replaces the record, declared fields and relations missing from the body are cleared.
+func (ctrl CatalogController) Update(ctx *gin.Context) {
+	ctrl.update(ctx, true)
+}
+
+// Patch applies a JSON Merge Patch, only the fields and relations sent are changed and null clears them.
+func (ctrl CatalogController) Patch(ctx *gin.Context) {
+	ctrl.update(ctx, false)
+}
+
+func (ctrl CatalogController) update(ctx *gin.Context, replace bool) {
+	ctrl.Init(ctx)
+
+	transformer, err := transformers.Get("request/" + ctrl.PluralName + "/update")
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	input, ok := updateInput(ctx, transformer, replace)
+	if !ok {
+		return
+	}
+
+	values, relations := updateValues(transformer, input, replace)
+	protectOwner(ctx, ctrl.PluralName, values)
+
+	hasMany, _ := transformer["has_many"].(map[string]any)
+	manyToMany, _ := transformer["many_to_many"].(map[string]any)
+
+	if duplicate, ok := transformer["duplicate"]; ok {
+		values["duplicate"] = duplicate
+
+		for i := range hasMany {
+			transformerValues, _ := relations[i].([]any)
+			if len(transformerValues) == 0 {
+				continue
+			}
+
+			defaultItem := utils.FilterMap(transformerValues, func(item map[string]any) bool {
+				_, itemDefaultExist := item["default"]
+				if itemDefaultExist {
+					isDefaultItem := item["default"].(bool)
+					return isDefaultItem == true
+				}
+				return false
+			})
+
+			if len(defaultItem) != 0 {
+				utils.SetDoubleRecord(values, defaultItem[0], i)
+			} else {
+				utils.SetDoubleRecord(values, transformerValues[0].(map[string]any), i)
 			}
 		}
 
-		if err := tx.Table(ctrl.PluralName).Where("id = ?", ctx.Param("id")).Updates(&transformer).Error; err != nil {
+		delete(values, "duplicate")
+	}
+
+	if err := database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.PluralName); err != nil {
 			return err
 		}
 
-		if hasMany != nil {
-			for i, v := range hasMany.(map[string]any) {
-				if transformer[i] == nil {
-					continue
-				}
+		if err := checkExists(ctx, tx, ctrl.PluralName, ctrl.SingularLabel); err != nil {
+			return err
+		}
 
-				table := v.(map[string]any)["table"].(string)
-				fk := v.(map[string]any)["fk"].(string)
+		if err := checkIfMatch(ctx, tx, ctrl.PluralName); err != nil {
+			return err
+		}
 
-				if err = tx.Table(table).Where(fk+" = ?", ctx.Param("id")).Delete(map[string]any{}).Error; err != nil {
-					return err
-				}
+		// relations are part of the record, rewriting them is a new revision as well
+		if touched := database.Touch(tx, ctrl.PluralName, values); len(values) > 0 || (len(relations) > 0 && len(touched) > 0) {
+			if err := tx.Table(ctrl.PluralName).Where("id = ?", ctx.Param("id")).Updates(touched).Error; err != nil {
+				return err
+			}
+		}
 
-				items := utils.Prepare1toM(fk, ctx.Param("id"), hasManyItems[i])
+		for i, v := range hasMany {
+			hasManyItems, ok := relations[i]
+			if !ok {
+				continue
+			}
+
+			table := v.(map[string]any)["table"].(string)
+			fk := v.(map[string]any)["fk"].(string)
+
+			if err = tx.Table(table).Where(fk+" = ?", ctx.Param("id")).Delete(map[string]any{}).Error; err != nil {
+				return err
+			}
+
+			items := []map[string]any{}
+			if list, _ := hasManyItems.([]any); len(list) > 0 {
+				items = utils.Prepare1toM(fk, ctx.Param("id"), list)
 
 				if err = tx.Table(table).Create(&items).Error; err != nil {
 					return err
 				}
-
-				transformer[i] = items
 			}
+
+			values[i] = items
 		}
 
-		if manyToMany != nil {
-			for i, v := range manyToMany.(map[string]any) {
-				if transformer[i] == nil {
-					continue
-				}
-				table := v.(map[string]any)["table"].(string)
-				fk1 := v.(map[string]any)["fk_1"].(string)
-				fk2 := v.(map[string]any)["fk_2"].(string)
+		for i, v := range manyToMany {
+			hasManyToManyGroups, ok := relations[i]
+			if !ok {
+				continue
+			}
 
-				if err = tx.Table(table).Where(fk1+" = ?", ctx.Param("id")).Delete(map[string]any{}).Error; err != nil {
-					return err
-				}
+			table := v.(map[string]any)["table"].(string)
+			fk1, fk2 := queries.PivotKeys(v.(map[string]any))
+			if fk1 == "" || fk2 == "" {
+				return fmt.Errorf("many_to_many %v needs fk_1 and fk_2", i)
+			}
 
-				if err = tx.Table(ctrl.PluralName).Where("id = ?", ctx.Param("id")).Take(&transformer).Error; err != nil {
-					return err
-				}
+			if err = tx.Table(table).Where(fk1+" = ?", ctx.Param("id")).Delete(map[string]any{}).Error; err != nil {
+				return err
+			}
 
-				groups := utils.PrepareMtoM(fk1, ctx.Param("id"), fk2, hasManyToManyGroups[i])
+			groups := []map[string]any{}
+			if list, _ := hasManyToManyGroups.([]any); len(list) > 0 {
+				groups = utils.PrepareMtoM(fk1, ctx.Param("id"), fk2, list)
 
 				if err = tx.Table(table).Create(&groups).Error; err != nil {
 					return err
 				}
-				transformer[i] = groups
 			}
+
+			values[i] = groups
 		}
 
-		return nil
+		return refreshETag(ctx, tx, ctrl.PluralName)
 	}); err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	ctrl.Find(ctx)
-
-	ctx.JSON(http.StatusOK, utils.ResponseData("success", "update "+ctrl.SingularLabel+" success", transformer))
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "update "+ctrl.SingularLabel+" success", values))
 }
 
 // todo : need to check constraint error
 func (ctrl CatalogController) Delete(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	if err := utils.DB.Table(ctrl.PluralName).Where("id = ?", ctx.Param("id")).Delete(map[string]any{}).Error; err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+	if err := database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		return database.Delete(tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")), ctrl.Table).Error
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, utils.ResponseData("success", "delete "+ctrl.SingularLabel+" success", nil))
 }
 
+func (ctrl CatalogController) ForceDelete(ctx *gin.Context) {
+	ctrl.Init(ctx)
+
+	if err := database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		return tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")).Delete(map[string]any{}).Error
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "force delete "+ctrl.SingularLabel+" success", nil))
+}
+
+func (ctrl CatalogController) Restore(ctx *gin.Context) {
+	ctrl.Init(ctx)
+
+	db := database.FromContext(ctx)
+
+	if !database.SoftDeletes(db, ctrl.Table) {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", ctrl.SingularLabel+" doesn't support soft delete", nil))
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		result := database.Restore(tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")), ctrl.Table)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return notFound("deleted " + ctrl.SingularLabel)
+		}
+
+		return refreshETag(ctx, tx, ctrl.Table)
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "restore "+ctrl.SingularLabel+" success", nil))
+}
+
 func (ctrl CatalogController) DeleteByQuery(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/request/" + ctrl.PluralName + "/delete.json")
+	transformer, err := transformers.Get("request/" + ctrl.Table + "/delete")
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	query := utils.DB.Table(ctrl.PluralName)
-	utils.SetFilterByQuery(query, transformer, ctx)
+	query := database.FromContext(ctx).Table(ctrl.Table)
+	filter, err := queries.SetFilterByQuery(query, ctrl.Table, transformer, ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	// without a filter every row would be deleted
+	if len(filter) == 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "delete by query needs at least one filter", nil))
+		return
+	}
+
+	scopeOwner(ctx, query, ctrl.Table)
 
-	if err := query.Delete(map[string]any{}).Error; err != nil {
+	if err := database.Delete(query, ctrl.Table).Error; err != nil {
 		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
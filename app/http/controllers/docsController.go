@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/62teknologi/62whale/app/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DocsController struct {
+	Routes func() gin.RoutesInfo
+}
+
+func (ctrl DocsController) Spec(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, openapi.Build(ctrl.Routes()))
+}
+
+func (ctrl DocsController) UI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", openapi.SwaggerUI)
+}
+
+// Asset serves a vendored swagger-ui file, eg: /api/docs/assets/swagger-ui.css.
+func (ctrl DocsController) Asset(ctx *gin.Context) {
+	ctx.FileFromFS(ctx.Param("file"), http.FS(openapi.SwaggerAssets))
+}
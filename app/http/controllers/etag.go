@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/queries"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errPreconditionFailed = errors.New("record has been modified, fetch it again before writing")
+
+var errNotFound = errors.New("not found")
+
+// notFound is the error of a write on a missing record, eg: "product not found".
+func notFound(label string) error {
+	return fmt.Errorf("%s %w", label, errNotFound)
+}
+
+// etagOf identifies a revision of a record from its version column, or its updated_at timestamp which can't tell
+// apart two writes within the precision of the column.
+func etagOf(value map[string]any) string {
+	if version, ok := value[database.Version]; ok && version != nil {
+		return `"v` + queries.Key(version) + `"`
+	}
+
+	switch updatedAt := value[database.UpdatedAt].(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return `"t` + strconv.FormatInt(updatedAt.UnixNano(), 36) + `"`
+	default:
+		hash := fnv.New64a()
+		hash.Write([]byte(queries.Key(updatedAt)))
+		return `"t` + strconv.FormatUint(hash.Sum64(), 36) + `"`
+	}
+}
+
+func setETag(ctx *gin.Context, value map[string]any) {
+	if etag := etagOf(value); etag != "" {
+		ctx.Header("ETag", etag)
+	}
+}
+
+// etagMatches compares an If-Match header with the current ETag using the strong comparison.
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || (etag != "" && candidate == etag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// revision reads the columns an ETag is built from, locking the row until the transaction ends.
+func revision(tx *gorm.DB, table string, id string) (map[string]any, error) {
+	columns := append([]string{"id"}, database.RevisionColumns(tx, table)...)
+
+	value := map[string]any{}
+	err := tx.Table(table).Clauses(clause.Locking{Strength: "UPDATE"}).Select(columns).Where("id = ?", id).Take(&value).Error
+
+	return value, err
+}
+
+// checkExists refuses a write on a missing record before any of its relations is touched.
+func checkExists(ctx *gin.Context, tx *gorm.DB, table string, label string) error {
+	_, err := revision(tx, table, ctx.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound(label)
+	}
+
+	return err
+}
+
+// checkIfMatch refuses the write when the If-Match header doesn't match the current revision of the record.
+func checkIfMatch(ctx *gin.Context, tx *gorm.DB, table string) error {
+	header := ctx.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	value, err := revision(tx, table, ctx.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errPreconditionFailed
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !etagMatches(header, etagOf(value)) {
+		return errPreconditionFailed
+	}
+
+	return nil
+}
+
+// refreshETag sends the ETag of the record revision written by the current transaction.
+func refreshETag(ctx *gin.Context, tx *gorm.DB, table string) error {
+	value, err := revision(tx, table, ctx.Param("id"))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	setETag(ctx, value)
+
+	return nil
+}
+
+// writeStatus is the status answered when a write fails.
+func writeStatus(err error) int {
+	if errors.Is(err, errPreconditionFailed) {
+		return http.StatusPreconditionFailed
+	}
+
+	if errors.Is(err, errNotOwned) || errors.Is(err, errNotFound) {
+		return http.StatusNotFound
+	}
+
+	return http.StatusBadRequest
+}
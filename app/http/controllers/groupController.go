@@ -4,11 +4,14 @@ import (
 	"net/http"
 
 	"github.com/62teknologi/62whale/62golib/utils"
-	"github.com/62teknologi/62whale/config"
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/queries"
+	"github.com/62teknologi/62whale/app/transformers"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gosimple/slug"
+	"gorm.io/gorm"
 )
 
 type GroupController struct {
@@ -33,15 +36,26 @@ func (ctrl GroupController) Find(ctx *gin.Context) {
 	value := map[string]any{}
 	columns := []string{ctrl.Table + ".*"}
 	order := "id desc"
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/response/" + ctrl.Table + "/find.json")
+	transformer, err := transformers.Get("response/" + ctrl.Table + "/find")
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	query := utils.DB.Table(ctrl.Table)
+	query := database.FromContext(ctx).Table(ctrl.Table)
+	queries.SetTrashedByQuery(query, ctrl.Table, ctx)
 
 	utils.SetOrderByQuery(query, ctx)
+	if err := queries.SetIncludes(ctx, transformer); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if err := queries.SetFields(ctx, database.FromContext(ctx), ctrl.Table, transformer, &columns); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
 	utils.SetBelongsTo(query, transformer, &columns, ctx)
 
 	delete(transformer, "filterable")
@@ -54,6 +68,8 @@ func (ctrl GroupController) Find(ctx *gin.Context) {
 	utils.MapValuesShifter(transformer, value)
 	utils.AttachBelongsTo(transformer, value)
 
+	setETag(ctx, value)
+
 	ctx.JSON(http.StatusOK, utils.ResponseData("success", "find "+ctrl.SingularLabel+" success", transformer))
 }
 
@@ -63,29 +79,69 @@ func (ctrl GroupController) FindAll(ctx *gin.Context) {
 	values := []map[string]any{}
 	columns := []string{ctrl.Table + ".*"}
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/response/" + ctrl.Table + "/find.json")
+	transformer, err := transformers.Get("response/" + ctrl.Table + "/find")
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	query := utils.DB.Table(ctrl.Table)
-	filter := utils.SetFilterByQuery(query, transformer, ctx)
+	query := database.FromContext(ctx).Table(ctrl.Table)
+	queries.SetTrashedByQuery(query, ctrl.Table, ctx)
+	filter, err := queries.SetFilterByQuery(query, ctrl.Table, transformer, ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
 	search := utils.SetGlobalSearch(query, transformer, ctx)
 
-	utils.SetOrderByQuery(query, ctx)
+	cursor, err := queries.NewCursor(ctx, database.FromContext(ctx), ctrl.Table)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if cursor == nil {
+		if err := queries.SetOrderByQuery(query, ctrl.Table, transformer, ctx); err != nil {
+			ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+			return
+		}
+	}
+
+	if err := queries.SetIncludes(ctx, transformer); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	if err := queries.SetFields(ctx, database.FromContext(ctx), ctrl.Table, transformer, &columns); err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
 	utils.SetBelongsTo(query, transformer, &columns, ctx)
 
 	delete(transformer, "filterable")
 	delete(transformer, "searchable")
 
-	pagination := utils.SetPagination(query, ctx)
+	var pagination map[string]any
+	if cursor != nil {
+		if pagination, err = cursor.Apply(query, &columns); err != nil {
+			ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+			return
+		}
+	} else {
+		pagination = utils.SetPagination(query, ctx)
+	}
 
 	if err := query.Select(columns).Find(&values).Error; err != nil {
 		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", ctrl.PluralLabel+" not found", nil))
 		return
 	}
 
+	if cursor != nil {
+		values = cursor.Page(values, pagination)
+	}
+
 	customResponses := utils.MultiMapValuesShifter(transformer, values)
 	summary := utils.GetSummary(transformer, values)
 
@@ -95,13 +151,14 @@ func (ctrl GroupController) FindAll(ctx *gin.Context) {
 func (ctrl GroupController) Create(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/request/" + ctrl.Table + "/create.json")
+	transformer, err := transformers.Get("request/" + ctrl.Table + "/create")
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
 	input := utils.ParseForm(ctx)
+	fillOwner(ctx, ctrl.Table, input)
 
 	if validation, err := utils.Validate(input, transformer); err {
 		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", validation.Errors))
@@ -117,8 +174,9 @@ func (ctrl GroupController) Create(ctx *gin.Context) {
 
 	utils.MapValuesShifter(transformer, input)
 	utils.MapNullValuesRemover(transformer)
+	fillOwner(ctx, ctrl.Table, transformer)
 
-	if err := utils.DB.Table(ctrl.Table).Create(&transformer).Error; err != nil {
+	if err := database.FromContext(ctx).Table(ctrl.Table).Create(&transformer).Error; err != nil {
 		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
@@ -126,65 +184,167 @@ func (ctrl GroupController) Create(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, utils.ResponseData("success", "create "+ctrl.SingularLabel+" success", transformer))
 }
 
+// Update replaces the record, declared fields missing from the body are cleared.
 func (ctrl GroupController) Update(ctx *gin.Context) {
+	ctrl.update(ctx, true)
+}
+
+// Patch applies a JSON Merge Patch, only the fields sent are changed and null clears a field.
+func (ctrl GroupController) Patch(ctx *gin.Context) {
+	ctrl.update(ctx, false)
+}
+
+func (ctrl GroupController) update(ctx *gin.Context, replace bool) {
 	ctrl.Init(ctx)
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/request/" + ctrl.Table + "/update.json")
+	transformer, err := transformers.Get("request/" + ctrl.Table + "/update")
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	input := utils.ParseForm(ctx)
-
-	if validation, err := utils.Validate(input, transformer); err {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", validation.Errors))
+	input, ok := updateInput(ctx, transformer, replace)
+	if !ok {
 		return
 	}
 
-	// not sure is it needed or not, may confusing if slug changes
-	if input["name"] != nil && transformer["slug"] == "" {
-		name, _ := input["name"].(string)
-		transformer["slug"] = slug.Make(name)
-	}
+	values, _ := updateValues(transformer, input, replace)
+	protectOwner(ctx, ctrl.Table, values)
 
-	utils.MapValuesShifter(transformer, input)
-	utils.MapNullValuesRemover(transformer)
+	if err := database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
 
-	if err := utils.DB.Table(ctrl.Table).Where("id = ?", ctx.Param("id")).Updates(&transformer).Error; err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		if err := checkExists(ctx, tx, ctrl.Table, ctrl.SingularLabel); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if len(values) > 0 {
+			if err := tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")).Updates(database.Touch(tx, ctrl.Table, values)).Error; err != nil {
+				return err
+			}
+		}
+
+		return refreshETag(ctx, tx, ctrl.Table)
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, utils.ResponseData("success", "update "+ctrl.SingularLabel+" success", transformer))
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "update "+ctrl.SingularLabel+" success", values))
 }
 
 // todo : need to check constraint error
 func (ctrl GroupController) Delete(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	if err := utils.DB.Table(ctrl.Table).Where("id = ?", ctx.Param("id")).Delete(map[string]any{}).Error; err != nil {
-		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+	if err := database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		return database.Delete(tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")), ctrl.Table).Error
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, utils.ResponseData("success", "delete "+ctrl.SingularLabel+" success", nil))
 }
 
+func (ctrl GroupController) ForceDelete(ctx *gin.Context) {
+	ctrl.Init(ctx)
+
+	if err := database.FromContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		return tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")).Delete(map[string]any{}).Error
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "force delete "+ctrl.SingularLabel+" success", nil))
+}
+
+func (ctrl GroupController) Restore(ctx *gin.Context) {
+	ctrl.Init(ctx)
+
+	db := database.FromContext(ctx)
+
+	if !database.SoftDeletes(db, ctrl.Table) {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", ctrl.SingularLabel+" doesn't support soft delete", nil))
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkOwner(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		if err := checkIfMatch(ctx, tx, ctrl.Table); err != nil {
+			return err
+		}
+
+		result := database.Restore(tx.Table(ctrl.Table).Where("id = ?", ctx.Param("id")), ctrl.Table)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return notFound("deleted " + ctrl.SingularLabel)
+		}
+
+		return refreshETag(ctx, tx, ctrl.Table)
+	}); err != nil {
+		ctx.JSON(writeStatus(err), utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "restore "+ctrl.SingularLabel+" success", nil))
+}
+
 func (ctrl GroupController) DeleteByQuery(ctx *gin.Context) {
 	ctrl.Init(ctx)
 
-	transformer, err := utils.JsonFileParser(config.Data.SettingPath + "/transformers/request/" + ctrl.PluralName + "/delete.json")
+	transformer, err := transformers.Get("request/" + ctrl.Table + "/delete")
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
 
-	query := utils.DB.Table(ctrl.PluralName)
-	utils.SetFilterByQuery(query, transformer, ctx)
+	query := database.FromContext(ctx).Table(ctrl.Table)
+	filter, err := queries.SetFilterByQuery(query, ctrl.Table, transformer, ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	// without a filter every row would be deleted
+	if len(filter) == 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "delete by query needs at least one filter", nil))
+		return
+	}
+
+	scopeOwner(ctx, query, ctrl.Table)
 
-	if err := query.Delete(map[string]any{}).Error; err != nil {
+	if err := database.Delete(query, ctrl.Table).Error; err != nil {
 		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", err.Error(), nil))
 		return
 	}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/transformers"
+	"github.com/62teknologi/62whale/app/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pingTimeout bounds the readiness check of a single data source.
+const pingTimeout = 2 * time.Second
+
+type HealthController struct{}
+
+// Live answers as long as the process serves requests.
+func (ctrl HealthController) Live(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "Server running well", nil))
+}
+
+// Ready checks every data source and the transformer registry, any component down answers 503.
+func (ctrl HealthController) Ready(ctx *gin.Context) {
+	components := map[string]any{}
+	ready := true
+
+	for _, name := range database.Names() {
+		db, _ := database.Get(name)
+		start := time.Now()
+		status := map[string]any{"status": "up"}
+
+		sqlDB, err := db.DB()
+		if err == nil {
+			ping, cancel := context.WithTimeout(ctx.Request.Context(), pingTimeout)
+			err = sqlDB.PingContext(ping)
+			cancel()
+		}
+
+		if err != nil {
+			ready = false
+			status["status"] = "down"
+			status["error"] = err.Error()
+		}
+
+		status["latency_ms"] = time.Since(start).Milliseconds()
+		components["database:"+name] = status
+	}
+
+	if count := len(transformers.Names()); count > 0 {
+		components["transformers"] = map[string]any{"status": "up", "count": count}
+	} else {
+		ready = false
+		components["transformers"] = map[string]any{"status": "down", "error": "no transformer loaded"}
+	}
+
+	if !ready {
+		ctx.JSON(http.StatusServiceUnavailable, utils.ResponseData("error", "Server not ready", components))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "Server ready", components))
+}
+
+// Version returns the build metadata of the binary.
+func (ctrl HealthController) Version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "version", version.Info()))
+}
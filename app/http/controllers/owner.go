@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/62teknologi/62whale/app/auth"
+	"github.com/62teknologi/62whale/app/transformers"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errNotOwned = errors.New("record not found")
+
+// ownership returns the owner column of table and the value owned by the principal.
+// Writes aren't restricted when the catalog declares no owner, nobody is authenticated or the principal is an admin.
+func ownership(ctx *gin.Context, table string) (string, any, bool) {
+	column := transformers.Owner(table)
+	principal, ok := auth.FromContext(ctx)
+
+	if column == "" || !ok || principal.IsAdmin() {
+		return column, nil, false
+	}
+
+	return column, principal.OwnerID(), true
+}
+
+// checkOwner refuses writes on a record the principal doesn't own.
+func checkOwner(ctx *gin.Context, tx *gorm.DB, table string) error {
+	column, owner, restricted := ownership(ctx, table)
+	if !restricted {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Table(table).Where("id = ?", ctx.Param("id")).Where(column+" = ?", owner).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return errNotOwned
+	}
+
+	return nil
+}
+
+// scopeOwner limits a query to the records owned by the principal.
+func scopeOwner(ctx *gin.Context, query *gorm.DB, table string) {
+	if column, owner, restricted := ownership(ctx, table); restricted {
+		query.Where(table+"."+column+" = ?", owner)
+	}
+}
+
+// fillOwner sets the owner of a new record from the principal instead of trusting the body, an admin may pick another owner.
+func fillOwner(ctx *gin.Context, table string, record map[string]any) {
+	column := transformers.Owner(table)
+	principal, ok := auth.FromContext(ctx)
+
+	if column == "" || !ok || (principal.IsAdmin() && record[column] != nil) {
+		return
+	}
+
+	record[column] = principal.OwnerID()
+}
+
+// protectOwner keeps non admins from handing their records over to someone else.
+func protectOwner(ctx *gin.Context, table string, values map[string]any) {
+	if column, _, restricted := ownership(ctx, table); restricted {
+		delete(values, column)
+	}
+}
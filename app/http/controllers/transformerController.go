@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/transformers"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransformerController struct{}
+
+func (ctrl TransformerController) Reload(ctx *gin.Context) {
+	if err := transformers.Reload(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, utils.ResponseData("error", err.Error(), nil))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, utils.ResponseData("success", "reload transformers success", transformers.Names()))
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/transformers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gosimple/slug"
+)
+
+// updateInput reads and validates an update body, writing the error response when it is refused.
+// PUT is validated against the whole update transformer, PATCH only against the keys it sends.
+func updateInput(ctx *gin.Context, transformer map[string]any, replace bool) (map[string]any, bool) {
+	if replace {
+		input := utils.ParseForm(ctx)
+
+		if validation, err := utils.Validate(input, transformer); err {
+			ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", validation.Errors))
+			return nil, false
+		}
+
+		return input, true
+	}
+
+	var patch map[string]any
+	if err := ctx.ShouldBindJSON(&patch); err != nil || patch == nil {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "body must be a JSON merge patch object", nil))
+		return nil, false
+	}
+
+	rules, present, nullErrors := patchRules(transformer, patch)
+
+	if len(nullErrors) > 0 {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", nullErrors))
+		return nil, false
+	}
+
+	if validation, err := utils.Validate(present, rules); err {
+		ctx.JSON(http.StatusBadRequest, utils.ResponseData("error", "validation", validation.Errors))
+		return nil, false
+	}
+
+	return patch, true
+}
+
+// patchRules narrows the update transformer to the keys sent by a patch so absent fields are not validated.
+// It returns the non null values to validate and the required fields the patch tries to clear.
+func patchRules(transformer map[string]any, patch map[string]any) (map[string]any, map[string]any, map[string]any) {
+	rules := map[string]any{}
+	present := map[string]any{}
+	nullErrors := map[string]any{}
+
+	for key, value := range transformer {
+		if transformers.ReservedKeys[key] {
+			rules[key] = value
+		}
+	}
+
+	for key, value := range patch {
+		rule, declared := transformer[key]
+		if !declared {
+			continue
+		}
+
+		if value == nil {
+			if isRequired(rule) {
+				nullErrors[key] = key + " can not be null"
+			}
+			continue
+		}
+
+		rules[key] = rule
+		present[key] = value
+	}
+
+	return rules, present, nullErrors
+}
+
+// updateValues resolves what an update writes. PUT replaces the record, a declared column missing from the input is cleared.
+// PATCH follows RFC 7396, only the keys present are written and null clears the column.
+// Relations are returned only when their children must be rewritten, a nil value meaning no children.
+func updateValues(transformer map[string]any, input map[string]any, replace bool) (map[string]any, map[string]any) {
+	columns := map[string]any{}
+	relations := map[string]any{}
+	relationNames := map[string]bool{}
+
+	for _, kind := range []string{"has_many", "many_to_many"} {
+		declared, _ := transformer[kind].(map[string]any)
+		for name := range declared {
+			relationNames[name] = true
+		}
+	}
+
+	for key, rule := range transformer {
+		if transformers.ReservedKeys[key] {
+			continue
+		}
+
+		value, sent := input[key]
+
+		if relationNames[key] {
+			if sent || replace {
+				relations[key] = value
+			}
+			continue
+		}
+
+		if _, isColumn := rule.(string); !isColumn {
+			continue
+		}
+
+		if sent || replace {
+			columns[key] = value
+		}
+	}
+
+	// not sure is it needed or not, may confusing if slug changes
+	if name, ok := input["name"].(string); ok && transformer["slug"] == "" {
+		columns["slug"] = slug.Make(name)
+	}
+
+	return columns, relations
+}
+
+func isRequired(rule any) bool {
+	value, _ := rule.(string)
+
+	for _, part := range strings.Split(value, "|") {
+		if part == "required" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/auth"
+	"github.com/62teknologi/62whale/app/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMiddleware requires credentials accepted by one of the authenticators and exposes the principal to controllers.
+func AuthMiddleware(authenticators []auth.Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(ctx)
+
+			if errors.Is(err, auth.ErrNoCredentials) {
+				continue
+			}
+
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, utils.ResponseData("error", err.Error(), nil))
+				return
+			}
+
+			// the credentials couldn't be checked, eg: the api key table is unreachable
+			if err != nil {
+				logger.FromContext(ctx).Error("authentication failed", "error", err)
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, utils.ResponseData("error", "authentication is unavailable", nil))
+				return
+			}
+
+			ctx.Set(auth.ContextKey, principal)
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, utils.ResponseData("error", "authentication required", nil))
+	}
+}
+
+// AdminMiddleware lets only the principals granted the admin role through, it must follow AuthMiddleware.
+func AdminMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := auth.FromContext(ctx)
+		if !ok || !principal.IsAdmin() {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, utils.ResponseData("error", "the "+auth.AdminRole+" role is required", nil))
+			return
+		}
+
+		ctx.Next()
+	}
+}
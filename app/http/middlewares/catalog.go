@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/catalogs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CatalogMiddleware only lets through the tables of the catalog registry, with the operation enabled.
+func CatalogMiddleware(kind string, operation string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		table := catalogs.TableOf(kind, ctx.Param("table"))
+
+		if !catalogs.Exists(table) {
+			ctx.AbortWithStatusJSON(http.StatusNotFound, utils.ResponseData("error", "catalog "+ctx.Param("table")+" not found", nil))
+			return
+		}
+
+		if !catalogs.Allowed(table, operation) {
+			ctx.AbortWithStatusJSON(http.StatusMethodNotAllowed, utils.ResponseData("error", operation+" is disabled for catalog "+ctx.Param("table"), nil))
+			return
+		}
+
+		ctx.Set(catalogs.ContextKey, table)
+		ctx.Next()
+	}
+}
@@ -1,19 +1,37 @@
 package middlewares
 
 import (
+	"net/http"
+
 	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/database"
 
 	"github.com/gin-gonic/gin"
 )
 
+// DbSourceHeader selects a named data source, the "db" query parameter is accepted as well.
+const DbSourceHeader = "X-Db-Source"
+
 func DbSelectorMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		name := ctx.GetHeader(DbSourceHeader)
+
+		if name == "" {
+			name = ctx.Query("db")
+		}
 
-		if ctx.Query("db") == "2" {
-			utils.DB = utils.DB2
-		} else {
-			utils.DB = utils.DB1
+		if name == "" {
+			name = database.DefaultName()
 		}
+
+		db, ok := database.Get(name)
+
+		if !ok {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, utils.ResponseData("error", "data source "+name+" is not configured", nil))
+			return
+		}
+
+		ctx.Set(database.ContextKey, db)
 		ctx.Next()
 	}
 }
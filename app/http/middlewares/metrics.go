@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/62teknologi/62whale/app/catalogs"
+	"github.com/62teknologi/62whale/app/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware counts and times every request by route template and catalog table.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		// only tables accepted by the catalog middleware are labelled, unknown names would grow the series without bound
+		table := ctx.GetString(catalogs.ContextKey)
+
+		metrics.HTTPRequests.WithLabelValues(ctx.Request.Method, route, table, strconv.Itoa(ctx.Writer.Status())).Inc()
+		metrics.HTTPDuration.WithLabelValues(ctx.Request.Method, route, table).Observe(time.Since(start).Seconds())
+	}
+}
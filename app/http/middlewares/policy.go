@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/auth"
+	"github.com/62teknologi/62whale/app/catalogs"
+	"github.com/62teknologi/62whale/app/transformers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyMiddleware enforces the role policy of the catalog, if any, on the operation and on the fields written.
+func PolicyMiddleware(kind string, operation string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		table := catalogs.TableOf(kind, ctx.Param("table"))
+
+		policy, ok := auth.PolicyOf(table)
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		principal, _ := auth.FromContext(ctx)
+
+		if !policy.Allows(principal, operation) {
+			if principal == nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, utils.ResponseData("error", "authentication required", nil))
+				return
+			}
+
+			ctx.AbortWithStatusJSON(http.StatusForbidden, utils.ResponseData("error", operation+" is not allowed on catalog "+ctx.Param("table"), nil))
+			return
+		}
+
+		if transformers.ReadOperations[operation] || len(policy.Fields) == 0 {
+			ctx.Next()
+			return
+		}
+
+		for _, field := range writtenFields(ctx, relationsOf(table, operation)) {
+			if !policy.CanWrite(principal, field) {
+				ctx.AbortWithStatusJSON(http.StatusForbidden, utils.ResponseData("error", "field "+field+" can't be written", nil))
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// relationsOf returns the has_many relations declared by the request transformer of a write operation.
+func relationsOf(table string, operation string) map[string]any {
+	name := "create"
+	if operation == "update" || operation == "patch" {
+		name = "update"
+	}
+
+	transformer, err := transformers.Get("request/" + table + "/" + name)
+	if err != nil {
+		return nil
+	}
+
+	relations, _ := transformer["has_many"].(map[string]any)
+
+	return relations
+}
+
+// writtenFields lists the keys sent in the body, of every record for a bulk array. The keys of the children of a declared
+// has_many relation are listed as "<relation>.<key>", eg: "items.price". The body is left readable for the controller.
+func writtenFields(ctx *gin.Context, relations map[string]any) []string {
+	if !strings.HasPrefix(ctx.ContentType(), "application/") || !strings.HasSuffix(ctx.ContentType(), "json") {
+		if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+			return nil
+		}
+
+		fields := []string{}
+		for key := range ctx.Request.PostForm {
+			fields = append(fields, formFields(key)...)
+		}
+
+		return fields
+	}
+
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil
+	}
+
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var records []map[string]any
+	var record map[string]any
+
+	if json.Unmarshal(body, &record) == nil {
+		records = append(records, record)
+	} else {
+		json.Unmarshal(body, &records)
+	}
+
+	fields := []string{}
+	for _, record := range records {
+		fields = recordFields(fields, "", record, relations)
+	}
+
+	return fields
+}
+
+// recordFields appends the keys of record, then those of the children of its has_many relations.
+func recordFields(fields []string, prefix string, record map[string]any, relations map[string]any) []string {
+	for key, value := range record {
+		fields = append(fields, prefix+key)
+
+		options, ok := relations[key].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		nested, _ := options["has_many"].(map[string]any)
+		children, _ := value.([]any)
+
+		for _, child := range children {
+			if child, ok := child.(map[string]any); ok {
+				fields = recordFields(fields, prefix+key+".", child, nested)
+			}
+		}
+	}
+
+	return fields
+}
+
+// formFields turns a form key into the fields it writes, eg: "items[0][price]" writes "items" and "items.price".
+func formFields(key string) []string {
+	fields := []string{}
+	path := ""
+
+	for _, part := range strings.Split(strings.ReplaceAll(key, "]", ""), "[") {
+		if _, err := strconv.Atoi(part); err == nil || part == "" {
+			continue
+		}
+
+		if path != "" {
+			path += "."
+		}
+
+		path += part
+		fields = append(fields, path)
+	}
+
+	return fields
+}
@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDMiddleware identifies the request with the X-Request-ID sent by the client or a generated one.
+// The id is echoed in the response headers and error bodies, and every request is logged once it is served.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(logger.RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		ctx.Set(logger.RequestIDKey, id)
+		ctx.Header(logger.RequestIDHeader, id)
+		ctx.Writer = &envelopeWriter{ResponseWriter: ctx.Writer, id: id}
+
+		start := time.Now()
+
+		ctx.Next()
+
+		log := logger.FromContext(ctx)
+		attrs := []any{
+			"method", ctx.Request.Method,
+			"path", ctx.Request.URL.Path,
+			"status", ctx.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", ctx.ClientIP(),
+		}
+
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, "errors", ctx.Errors.String())
+		}
+
+		if ctx.Writer.Status() >= http.StatusInternalServerError {
+			log.Error("request served", attrs...)
+			return
+		}
+
+		log.Info("request served", attrs...)
+	}
+}
+
+// RecoveryMiddleware answers 500 with the error envelope when a handler panics.
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, err any) {
+		logger.FromContext(ctx).Error("panic recovered", "error", err)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, utils.ResponseData("error", "internal server error", nil))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// envelopeWriter adds the request id to the JSON body of error responses.
+type envelopeWriter struct {
+	gin.ResponseWriter
+	id string
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	if w.Status() < http.StatusBadRequest || !strings.Contains(w.Header().Get("Content-Type"), "json") {
+		return w.ResponseWriter.Write(data)
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil || body == nil {
+		return w.ResponseWriter.Write(data)
+	}
+
+	body[logger.RequestIDKey] = w.id
+
+	enveloped, err := json.Marshal(body)
+	if err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+
+	if _, err := w.ResponseWriter.Write(enveloped); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
@@ -7,6 +7,14 @@ type Crud interface {
 	FindAll(*gin.Context)
 	Create(*gin.Context)
 	Update(*gin.Context)
+	Patch(*gin.Context)
 	Delete(*gin.Context)
 	DeleteByQuery(*gin.Context)
+	Restore(*gin.Context)
+	ForceDelete(*gin.Context)
+}
+
+// BulkCreator is implemented by controllers accepting an array of records in one request.
+type BulkCreator interface {
+	BulkCreate(*gin.Context)
 }
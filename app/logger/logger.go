@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/62teknologi/62whale/config"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+// RequestIDHeader carries the request id, read from the client when sent and echoed in every response.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey stores the request id in the gin context, log lines and error responses.
+const RequestIDKey = "request_id"
+
+var levels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// Setup replaces the default logger with one writing to stderr in the configured format and level.
+func Setup(cfg config.Config) error {
+	level, ok := levels[strings.ToLower(cfg.LogLevel)]
+	if !ok {
+		return fmt.Errorf("unknown log level %q, expected debug, info, warn or error", cfg.LogLevel)
+	}
+
+	options := slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(cfg.LogFormat) {
+	case "json":
+		slog.SetDefault(slog.New(options.NewJSONHandler(os.Stderr)))
+	case "text":
+		slog.SetDefault(slog.New(options.NewTextHandler(os.Stderr)))
+	default:
+		return fmt.Errorf("unknown log format %q, expected json or text", cfg.LogFormat)
+	}
+
+	return nil
+}
+
+// FromContext returns the default logger bound to the id of the request.
+func FromContext(ctx *gin.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With(RequestIDKey, id)
+	}
+
+	return slog.Default()
+}
+
+// RequestID returns the id set by the request id middleware.
+func RequestID(ctx *gin.Context) string {
+	return ctx.GetString(RequestIDKey)
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const startKey = "metrics:start"
+
+// gormPlugin times every statement of a data source through gorm callbacks.
+type gormPlugin struct {
+	source string
+}
+
+func (p *gormPlugin) Name() string {
+	return "metrics:" + p.source
+}
+
+func (p *gormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	name := p.Name()
+
+	errs := []error{
+		callback.Create().Before("gorm:create").Register(name+":before_create", p.before),
+		callback.Create().After("gorm:create").Register(name+":after_create", p.after("create")),
+		callback.Query().Before("gorm:query").Register(name+":before_query", p.before),
+		callback.Query().After("gorm:query").Register(name+":after_query", p.after("query")),
+		callback.Update().Before("gorm:update").Register(name+":before_update", p.before),
+		callback.Update().After("gorm:update").Register(name+":after_update", p.after("update")),
+		callback.Delete().Before("gorm:delete").Register(name+":before_delete", p.before),
+		callback.Delete().After("gorm:delete").Register(name+":after_delete", p.after("delete")),
+		callback.Row().Before("gorm:row").Register(name+":before_row", p.before),
+		callback.Row().After("gorm:row").Register(name+":after_row", p.after("row")),
+		callback.Raw().Before("gorm:raw").Register(name+":before_raw", p.before),
+		callback.Raw().After("gorm:raw").Register(name+":after_raw", p.after("raw")),
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *gormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func (p *gormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+
+		start, _ := value.(time.Time)
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+
+		DBQueries.WithLabelValues(p.source, table, operation, status).Inc()
+		DBDuration.WithLabelValues(p.source, table, operation).Observe(time.Since(start).Seconds())
+	}
+}
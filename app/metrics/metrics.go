@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "whale"
+
+var (
+	// HTTPRequests counts the served requests per route template and catalog table.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route, catalog table and status code.",
+	}, []string{"method", "route", "table", "status"})
+
+	// HTTPDuration observes the request latency per route template and catalog table.
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route and catalog table.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "table"})
+
+	// DBQueries counts the statements run through gorm per data source and table.
+	DBQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_queries_total",
+		Help:      "Database statements, by data source, table, operation and status.",
+	}, []string{"source", "table", "operation", "status"})
+
+	// DBDuration observes the statement latency per data source and table.
+	DBDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database statement latency, by data source, table and operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"source", "table", "operation"})
+
+	// TransformerLookups counts the transformer registry reads, "hit" when the transformer is registered and "miss" otherwise.
+	TransformerLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transformer_lookups_total",
+		Help:      "Transformer registry lookups, by result.",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(HTTPRequests, HTTPDuration, DBQueries, DBDuration, TransformerLookups)
+}
+
+// Instrument records the statements and the connection pool stats of a data source.
+func Instrument(source string, db *gorm.DB) error {
+	if err := db.Use(&gormPlugin{source: source}); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return prometheus.Register(collectors.NewDBStatsCollector(sqlDB, source))
+}
+
+// Handler exposes the metrics in the Prometheus text format.
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect renders DDL statements for a database driver.
+type Dialect struct {
+	Driver string
+}
+
+func NewDialect(driver string) (Dialect, error) {
+	switch driver {
+	case "mysql", "postgres", "sqlite":
+		return Dialect{Driver: driver}, nil
+	}
+
+	return Dialect{}, fmt.Errorf("migrations are not supported for database driver %q", driver)
+}
+
+func (d Dialect) Quote(name string) string {
+	if d.Driver == "mysql" {
+		return "`" + name + "`"
+	}
+
+	return `"` + name + `"`
+}
+
+// ColumnDefinition renders a column as used in CREATE TABLE and ADD COLUMN.
+func (d Dialect) ColumnDefinition(column *Column) string {
+	if column.Type == TypeID {
+		if d.Driver == "mysql" {
+			return d.Quote(column.Name) + " BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY"
+		}
+		if d.Driver == "sqlite" {
+			return d.Quote(column.Name) + " INTEGER PRIMARY KEY AUTOINCREMENT"
+		}
+		return d.Quote(column.Name) + " BIGSERIAL PRIMARY KEY"
+	}
+
+	definition := d.Quote(column.Name) + " " + d.columnType(column)
+
+	if column.Nullable {
+		definition += " NULL"
+	} else {
+		definition += " NOT NULL"
+	}
+
+	switch column.Default {
+	case "":
+	case "now":
+		definition += " DEFAULT CURRENT_TIMESTAMP"
+		if d.Driver == "mysql" && column.Name == "updated_at" {
+			definition += " ON UPDATE CURRENT_TIMESTAMP"
+		}
+	default:
+		definition += " DEFAULT " + column.Default
+	}
+
+	return definition
+}
+
+func (d Dialect) columnType(column *Column) string {
+	switch column.Type {
+	case TypeBigint:
+		if d.Driver == "mysql" && strings.HasSuffix(column.Name, "_id") {
+			return "BIGINT UNSIGNED"
+		}
+		return "BIGINT"
+	case TypeString:
+		size := column.Size
+		if size == 0 {
+			size = 255
+		}
+		return "VARCHAR(" + strconv.Itoa(size) + ")"
+	case TypeTimestamp:
+		return "TIMESTAMP"
+	}
+
+	return "TEXT"
+}
+
+func (d Dialect) CreateTable(table *Table) []string {
+	definitions := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		definitions[i] = "    " + d.ColumnDefinition(column)
+	}
+
+	statements := []string{"CREATE TABLE " + d.Quote(table.Name) + " (\n" + strings.Join(definitions, ",\n") + "\n)"}
+
+	for _, column := range table.Columns {
+		if column.Index {
+			statements = append(statements, d.CreateIndex(table.Name, column.Name))
+		}
+	}
+
+	return statements
+}
+
+func (d Dialect) DropTable(name string) string {
+	return "DROP TABLE " + d.Quote(name)
+}
+
+func (d Dialect) AddColumn(table string, column *Column) []string {
+	statements := []string{"ALTER TABLE " + d.Quote(table) + " ADD COLUMN " + d.ColumnDefinition(column)}
+
+	if column.Index {
+		statements = append(statements, d.CreateIndex(table, column.Name))
+	}
+
+	return statements
+}
+
+func (d Dialect) DropColumn(table string, column string) string {
+	return "ALTER TABLE " + d.Quote(table) + " DROP COLUMN " + d.Quote(column)
+}
+
+func (d Dialect) CreateIndex(table string, column string) string {
+	return "CREATE INDEX " + d.Quote(indexName(table, column)) + " ON " + d.Quote(table) + " (" + d.Quote(column) + ")"
+}
+
+func (d Dialect) DropIndex(table string, column string) string {
+	if d.Driver == "mysql" {
+		return "DROP INDEX " + d.Quote(indexName(table, column)) + " ON " + d.Quote(table)
+	}
+
+	return "DROP INDEX " + d.Quote(indexName(table, column))
+}
+
+func indexName(table string, column string) string {
+	return "idx_" + table + "_" + column
+}
@@ -0,0 +1,145 @@
+package migrations
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewDialect(t *testing.T) {
+	for _, driver := range []string{"mysql", "postgres", "sqlite"} {
+		if _, err := NewDialect(driver); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+	}
+
+	if _, err := NewDialect("sqlserver"); err == nil {
+		t.Fatal("sqlserver: expected an unsupported driver error")
+	}
+}
+
+func TestColumnDefinition(t *testing.T) {
+	columns := []*Column{
+		{Name: "id", Type: TypeID},
+		{Name: "category_id", Type: TypeBigint, Nullable: true},
+		{Name: "title", Type: TypeString, Size: 120},
+		{Name: "slug", Type: TypeString, Nullable: true},
+		{Name: "body", Type: TypeText, Nullable: true},
+		{Name: "version", Type: TypeBigint, Default: "0"},
+		{Name: "updated_at", Type: TypeTimestamp, Nullable: true, Default: "now"},
+	}
+
+	expected := map[string][]string{
+		"mysql": {
+			"`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY",
+			"`category_id` BIGINT UNSIGNED NULL",
+			"`title` VARCHAR(120) NOT NULL",
+			"`slug` VARCHAR(255) NULL",
+			"`body` TEXT NULL",
+			"`version` BIGINT NOT NULL DEFAULT 0",
+			"`updated_at` TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP",
+		},
+		"postgres": {
+			`"id" BIGSERIAL PRIMARY KEY`,
+			`"category_id" BIGINT NULL`,
+			`"title" VARCHAR(120) NOT NULL`,
+			`"slug" VARCHAR(255) NULL`,
+			`"body" TEXT NULL`,
+			`"version" BIGINT NOT NULL DEFAULT 0`,
+			`"updated_at" TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP`,
+		},
+		"sqlite": {
+			`"id" INTEGER PRIMARY KEY AUTOINCREMENT`,
+			`"category_id" BIGINT NULL`,
+			`"title" VARCHAR(120) NOT NULL`,
+			`"slug" VARCHAR(255) NULL`,
+			`"body" TEXT NULL`,
+			`"version" BIGINT NOT NULL DEFAULT 0`,
+			`"updated_at" TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP`,
+		},
+	}
+
+	for driver, definitions := range expected {
+		dialect := Dialect{Driver: driver}
+
+		for i, column := range columns {
+			if definition := dialect.ColumnDefinition(column); definition != definitions[i] {
+				t.Errorf("%s %s: expected %s, got %s", driver, column.Name, definitions[i], definition)
+			}
+		}
+	}
+}
+
+func TestTableStatements(t *testing.T) {
+	table := &Table{Name: "post_tags", Columns: []*Column{
+		{Name: "id", Type: TypeID},
+		{Name: "post_id", Type: TypeBigint, Index: true},
+	}}
+
+	tests := []struct {
+		driver string
+		create []string
+		add    []string
+		drop   string
+		index  string
+	}{
+		{
+			driver: "mysql",
+			create: []string{
+				"CREATE TABLE `post_tags` (\n    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,\n    `post_id` BIGINT UNSIGNED NOT NULL\n)",
+				"CREATE INDEX `idx_post_tags_post_id` ON `post_tags` (`post_id`)",
+			},
+			add: []string{
+				"ALTER TABLE `post_tags` ADD COLUMN `post_id` BIGINT UNSIGNED NOT NULL",
+				"CREATE INDEX `idx_post_tags_post_id` ON `post_tags` (`post_id`)",
+			},
+			drop:  "ALTER TABLE `post_tags` DROP COLUMN `post_id`",
+			index: "DROP INDEX `idx_post_tags_post_id` ON `post_tags`",
+		},
+		{
+			driver: "postgres",
+			create: []string{
+				"CREATE TABLE \"post_tags\" (\n    \"id\" BIGSERIAL PRIMARY KEY,\n    \"post_id\" BIGINT NOT NULL\n)",
+				`CREATE INDEX "idx_post_tags_post_id" ON "post_tags" ("post_id")`,
+			},
+			add: []string{
+				`ALTER TABLE "post_tags" ADD COLUMN "post_id" BIGINT NOT NULL`,
+				`CREATE INDEX "idx_post_tags_post_id" ON "post_tags" ("post_id")`,
+			},
+			drop:  `ALTER TABLE "post_tags" DROP COLUMN "post_id"`,
+			index: `DROP INDEX "idx_post_tags_post_id"`,
+		},
+		{
+			driver: "sqlite",
+			create: []string{
+				"CREATE TABLE \"post_tags\" (\n    \"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n    \"post_id\" BIGINT NOT NULL\n)",
+				`CREATE INDEX "idx_post_tags_post_id" ON "post_tags" ("post_id")`,
+			},
+			add: []string{
+				`ALTER TABLE "post_tags" ADD COLUMN "post_id" BIGINT NOT NULL`,
+				`CREATE INDEX "idx_post_tags_post_id" ON "post_tags" ("post_id")`,
+			},
+			drop:  `ALTER TABLE "post_tags" DROP COLUMN "post_id"`,
+			index: `DROP INDEX "idx_post_tags_post_id"`,
+		},
+	}
+
+	for _, test := range tests {
+		dialect := Dialect{Driver: test.driver}
+
+		if create := dialect.CreateTable(table); !reflect.DeepEqual(create, test.create) {
+			t.Errorf("%s create table: expected %q, got %q", test.driver, test.create, create)
+		}
+
+		if add := dialect.AddColumn(table.Name, table.Columns[1]); !reflect.DeepEqual(add, test.add) {
+			t.Errorf("%s add column: expected %q, got %q", test.driver, test.add, add)
+		}
+
+		if drop := dialect.DropColumn(table.Name, "post_id"); drop != test.drop {
+			t.Errorf("%s drop column: expected %q, got %q", test.driver, test.drop, drop)
+		}
+
+		if index := dialect.DropIndex(table.Name, "post_id"); index != test.index {
+			t.Errorf("%s drop index: expected %q, got %q", test.driver, test.index, index)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/62teknologi/62whale/app/database"
+
+	"gorm.io/gorm"
+)
+
+// Table keeping track of the applied migrations.
+const historyTable = "whale_migrations"
+
+type Migration struct {
+	Version string
+	Name    string
+	Applied bool
+	dir     string
+}
+
+func (m Migration) file(direction string) string {
+	return filepath.Join(m.dir, m.Version+"_"+m.Name+"."+direction+".sql")
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.up\.sql$`)
+
+// Diff returns the statements bringing the live database to the schema and the statements reverting them.
+// Existing columns are never altered nor dropped.
+func Diff(db *gorm.DB, dialect Dialect, schema *Schema) (up []string, down []string, err error) {
+	migrator := db.Migrator()
+
+	for _, name := range schema.Names() {
+		table := schema.Tables[name]
+
+		if !migrator.HasTable(name) {
+			up = append(up, dialect.CreateTable(table)...)
+			down = append([]string{dialect.DropTable(name)}, down...)
+			continue
+		}
+
+		types, err := migrator.ColumnTypes(name)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		live := map[string]bool{}
+		for _, column := range types {
+			live[column.Name()] = true
+		}
+
+		for _, column := range table.Columns {
+			if live[column.Name] {
+				continue
+			}
+
+			up = append(up, dialect.AddColumn(name, column)...)
+
+			// sqlite refuses to drop an indexed column
+			revert := []string{dialect.DropColumn(name, column.Name)}
+			if column.Index {
+				revert = append([]string{dialect.DropIndex(name, column.Name)}, revert...)
+			}
+			down = append(revert, down...)
+		}
+	}
+
+	return up, down, nil
+}
+
+// Generate writes a new versioned migration into dir, it returns false when there is nothing to migrate.
+func Generate(db *gorm.DB, dialect Dialect, dir string, name string) (Migration, bool, error) {
+	migrations, err := List(db, dir)
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	for _, migration := range migrations {
+		if !migration.Applied {
+			return Migration{}, false, fmt.Errorf("migration %s_%s is pending, apply it before generating a new one", migration.Version, migration.Name)
+		}
+	}
+
+	up, down, err := Diff(db, dialect, Derive())
+	if err != nil || len(up) == 0 {
+		return Migration{}, false, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Migration{}, false, err
+	}
+
+	migration := Migration{Version: time.Now().UTC().Format("20060102150405"), Name: name, dir: dir}
+
+	if err := os.WriteFile(migration.file("up"), []byte(render(up)), 0o644); err != nil {
+		return Migration{}, false, err
+	}
+
+	if err := os.WriteFile(migration.file("down"), []byte(render(down)), 0o644); err != nil {
+		return Migration{}, false, err
+	}
+
+	return migration, true, nil
+}
+
+// List returns the migrations found in dir ordered by version, flagged when already applied.
+func List(db *gorm.DB, dir string) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		migrations = append(migrations, Migration{Version: match[1], Name: match[2], Applied: applied[match[1]], dir: dir})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order.
+func Up(db *gorm.DB, dir string) ([]Migration, error) {
+	migrations, err := List(db, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+
+	for _, migration := range migrations {
+		if migration.Applied {
+			continue
+		}
+
+		if err := run(db, migration, "up", func(tx *gorm.DB) error {
+			return tx.Table(historyTable).Create(map[string]any{"version": migration.Version, "name": migration.Name}).Error
+		}); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the latest applied migrations.
+func Down(db *gorm.DB, dir string, steps int) ([]Migration, error) {
+	migrations, err := List(db, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if !migration.Applied {
+			continue
+		}
+
+		if err := run(db, migration, "down", func(tx *gorm.DB) error {
+			return tx.Table(historyTable).Where("version = ?", migration.Version).Delete(map[string]any{}).Error
+		}); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+func run(db *gorm.DB, migration Migration, direction string, record func(tx *gorm.DB) error) error {
+	content, err := os.ReadFile(migration.file(direction))
+	if err != nil {
+		return err
+	}
+
+	// the schema changes even when a statement fails on drivers committing DDL, eg: mysql
+	defer database.ResetColumns()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range split(tx.Dialector.Name(), string(content)) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("%s_%s %s: %w", migration.Version, migration.Name, direction, err)
+			}
+		}
+
+		return record(tx)
+	})
+}
+
+func appliedVersions(db *gorm.DB) (map[string]bool, error) {
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS " + historyTable + " (version VARCHAR(32) NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)").Error; err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	if err := db.Table(historyTable).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+
+	applied := map[string]bool{}
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	return applied, nil
+}
+
+func render(statements []string) string {
+	return strings.Join(statements, ";\n\n") + ";\n"
+}
+
+// split breaks a migration file into statements on the semicolons found outside of string literals, quoted
+// identifiers, dollar quoted bodies (postgres) and comments. Comments are dropped, a backslash escapes a quote on
+// mysql only.
+func split(driver string, content string) []string {
+	statements := []string{}
+	statement := strings.Builder{}
+
+	flush := func() {
+		if text := strings.TrimSpace(statement.String()); text != "" {
+			statements = append(statements, text)
+		}
+		statement.Reset()
+	}
+
+	for i := 0; i < len(content); {
+		rest := content[i:]
+
+		switch {
+		case rest[0] == ';':
+			flush()
+			i++
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			statement.WriteByte(' ')
+			i += end
+		case rest[0] == '\'' || rest[0] == '"' || rest[0] == '`':
+			end := quoted(rest, driver == "mysql")
+			statement.WriteString(rest[:end])
+			i += end
+		case driver == "postgres" && dollarTag.MatchString(rest):
+			tag := dollarTag.FindString(rest)
+			end := strings.Index(rest[len(tag):], tag)
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 2 * len(tag)
+			}
+			statement.WriteString(rest[:end])
+			i += end
+		default:
+			statement.WriteByte(rest[0])
+			i++
+		}
+	}
+
+	flush()
+
+	return statements
+}
+
+// dollarTag opens a postgres dollar quoted string, eg: $$ or $body$, unlike a $1 parameter.
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z_0-9]*)?\$`)
+
+// quoted returns the length of the quoted text content starts with, a doubled quote stands for itself.
+func quoted(content string, backslash bool) int {
+	quote := content[0]
+
+	for i := 1; i < len(content); i++ {
+		switch {
+		case backslash && content[i] == '\\':
+			i++
+		case content[i] == quote && i+1 < len(content) && content[i+1] == quote:
+			i++
+		case content[i] == quote:
+			return i + 1
+		}
+	}
+
+	return len(content)
+}
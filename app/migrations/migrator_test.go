@@ -0,0 +1,171 @@
+package migrations
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/62teknologi/62whale/app/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     string
+		content    string
+		statements []string
+	}{
+		{
+			name:       "statements",
+			content:    "CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\n",
+			statements: []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:       "statements on a line",
+			content:    "DELETE FROM a; DELETE FROM b",
+			statements: []string{"DELETE FROM a", "DELETE FROM b"},
+		},
+		{
+			name:       "string literal",
+			content:    "INSERT INTO a VALUES ('x;\ny', 'it''s; here');\nDELETE FROM b;",
+			statements: []string{"INSERT INTO a VALUES ('x;\ny', 'it''s; here')", "DELETE FROM b"},
+		},
+		{
+			name:       "quoted identifiers",
+			content:    "CREATE TABLE \"a;b\" (id INT);\nDELETE FROM `c;d`;",
+			statements: []string{"CREATE TABLE \"a;b\" (id INT)", "DELETE FROM `c;d`"},
+		},
+		{
+			name:       "comments",
+			content:    "-- first; second\nDELETE FROM a; -- trailing;\n/* block;\ncomment */ DELETE FROM b;\n-- last",
+			statements: []string{"DELETE FROM a", "DELETE FROM b"},
+		},
+		{
+			name:       "comment markers in literals",
+			content:    "INSERT INTO a VALUES ('-- not a comment;', '/* nor; this */');",
+			statements: []string{"INSERT INTO a VALUES ('-- not a comment;', '/* nor; this */')"},
+		},
+		{
+			name:       "backslash on mysql",
+			driver:     "mysql",
+			content:    `INSERT INTO a VALUES ('x\';y');DELETE FROM b;`,
+			statements: []string{`INSERT INTO a VALUES ('x\';y')`, "DELETE FROM b"},
+		},
+		{
+			name:       "backslash on postgres",
+			driver:     "postgres",
+			content:    `INSERT INTO a VALUES ('C:\');DELETE FROM b;`,
+			statements: []string{`INSERT INTO a VALUES ('C:\')`, "DELETE FROM b"},
+		},
+		{
+			name:    "dollar quoted body on postgres",
+			driver:  "postgres",
+			content: "CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n  NEW.updated_at := now();\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;\nSELECT $$a;b$$, $1;",
+			statements: []string{
+				"CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n  NEW.updated_at := now();\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql",
+				"SELECT $$a;b$$, $1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		driver := test.driver
+		if driver == "" {
+			driver = "sqlite"
+		}
+
+		if statements := split(driver, test.content); !reflect.DeepEqual(statements, test.statements) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.statements, statements)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	loadFixtures(t)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Exec(`CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, title VARCHAR(120) NOT NULL, body TEXT NULL)`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	dialect := Dialect{Driver: "sqlite"}
+
+	up, down, err := Diff(db, dialect, Derive())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, statement := range up {
+		if strings.Contains(statement, `"title"`) || strings.Contains(statement, `"body"`) {
+			t.Errorf("existing columns must be kept, got %s", statement)
+		}
+	}
+
+	for _, expected := range []string{
+		"CREATE TABLE \"post_categories\" (\n    \"id\" INTEGER PRIMARY KEY AUTOINCREMENT,\n    \"post_id\" BIGINT NOT NULL,\n    \"category_id\" BIGINT NOT NULL\n)",
+		`ALTER TABLE "posts" ADD COLUMN "version" BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE "posts" ADD COLUMN "user_id" BIGINT NULL`,
+		`CREATE INDEX "idx_posts_user_id" ON "posts" ("user_id")`,
+	} {
+		if !contains(up, expected) {
+			t.Errorf("up: %q missing from %q", expected, up)
+		}
+	}
+
+	if down[0] != `DROP INDEX "idx_posts_author_id"` || down[1] != `ALTER TABLE "posts" DROP COLUMN "author_id"` {
+		t.Errorf("down must drop the index of a column before the column, got %q", down)
+	}
+
+	if down[len(down)-2] != `DROP TABLE "post_tags"` || down[len(down)-1] != `DROP TABLE "post_categories"` {
+		t.Errorf("down must revert up in the reverse order, got %q", down)
+	}
+
+	dir := t.TempDir()
+
+	if database.HasColumn(db, "posts", "user_id") {
+		t.Fatal("posts.user_id must not exist before up")
+	}
+
+	migration, ok, err := Generate(db, dialect, dir, "posts")
+	if err != nil || !ok {
+		t.Fatalf("generate: expected a migration, got %v %v", ok, err)
+	}
+
+	if _, err := Up(db, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// the columns cached before the migration are looked up again
+	if !database.HasColumn(db, "posts", "user_id") {
+		t.Fatal("posts.user_id must be seen once up is applied")
+	}
+
+	if up, _, err := Diff(db, dialect, Derive()); err != nil || len(up) > 0 {
+		t.Fatalf("expected nothing left to migrate after up, got %q %v", up, err)
+	}
+
+	if _, err := Down(db, dir, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if db.Migrator().HasTable("post_tags") || database.HasColumn(db, "posts", "user_id") {
+		t.Fatalf("down of %s_%s must revert it", migration.Version, migration.Name)
+	}
+}
+
+func contains(statements []string, statement string) bool {
+	for _, s := range statements {
+		if s == statement {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,306 @@
+package migrations
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/queries"
+	"github.com/62teknologi/62whale/app/transformers"
+)
+
+// Column types understood by every dialect.
+const (
+	TypeID        = "id"
+	TypeBigint    = "bigint"
+	TypeString    = "string"
+	TypeText      = "text"
+	TypeTimestamp = "timestamp"
+)
+
+type Column struct {
+	Name     string
+	Type     string
+	Size     int
+	Nullable bool
+	Index    bool
+	// Default is empty, "now" or a literal value, eg: "0"
+	Default string
+}
+
+type Table struct {
+	Name    string
+	Columns []*Column
+	columns map[string]*Column
+}
+
+// Schema is the set of tables described by the transformer tree.
+type Schema struct {
+	Tables map[string]*Table
+}
+
+// Derive builds the expected schema from the transformers loaded in the registry.
+func Derive() *Schema {
+	schema := &Schema{Tables: map[string]*Table{}}
+	entities := map[string]bool{}
+	pivots := map[string][]string{}
+
+	for _, name := range transformers.Names() {
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 || parts[0] != "request" || parts[2] == transformers.PolicyFile {
+			continue
+		}
+
+		transformer, _ := transformers.Get(name)
+		entities[parts[1]] = true
+
+		table := schema.entity(parts[1])
+		table.Add(&Column{Name: "slug", Type: TypeString, Size: 255, Nullable: true, Index: true})
+		schema.addRequest(table, transformer, parts[2] == "create", pivots)
+
+		if owner := transformers.Owner(parts[1]); owner != "" {
+			table.Add(&Column{Name: owner, Type: TypeBigint, Nullable: true, Index: true})
+		}
+	}
+
+	for _, name := range transformers.Names() {
+		parts := strings.Split(name, "/")
+		if len(parts) != 3 || parts[0] != "response" {
+			continue
+		}
+
+		transformer, _ := transformers.Get(name)
+		schema.addResponse(schema.entity(parts[1]), transformer, pivots)
+	}
+
+	// a pivot sharing its table with an entity can't force its keys on the entity rows
+	for table, keys := range pivots {
+		for _, key := range keys {
+			if entities[table] {
+				schema.Tables[table].columns[key].Nullable = true
+			}
+		}
+	}
+
+	return schema
+}
+
+// Names returns the table names in a stable order.
+func (s *Schema) Names() []string {
+	names := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (s *Schema) table(name string) *Table {
+	if table, ok := s.Tables[name]; ok {
+		return table
+	}
+
+	table := &Table{Name: name, columns: map[string]*Column{}}
+	table.Add(&Column{Name: "id", Type: TypeID})
+	s.Tables[name] = table
+
+	return table
+}
+
+// entity returns a table carrying the timestamps and the version every catalog must have.
+func (s *Schema) entity(name string) *Table {
+	table := s.table(name)
+	// the ETag is taken from version, updated_at can't tell apart two writes within its precision
+	table.Add(&Column{Name: "version", Type: TypeBigint, Default: "0"})
+	table.Add(&Column{Name: "created_at", Type: TypeTimestamp, Nullable: true, Default: "now"})
+	table.Add(&Column{Name: "updated_at", Type: TypeTimestamp, Nullable: true, Default: "now"})
+	table.Add(&Column{Name: "deleted_at", Type: TypeTimestamp, Nullable: true})
+
+	return table
+}
+
+func (s *Schema) addRequest(table *Table, transformer map[string]any, create bool, pivots map[string][]string) {
+	hasMany, _ := transformer["has_many"].(map[string]any)
+	manyToMany, _ := transformer["many_to_many"].(map[string]any)
+
+	for _, key := range transformers.SortedKeys(transformer) {
+		if transformers.ReservedKeys[key] || hasMany[key] != nil || manyToMany[key] != nil {
+			continue
+		}
+
+		if rule, ok := transformer[key].(string); ok {
+			table.Add(ColumnFromRule(key, rule, create))
+		}
+	}
+
+	for _, name := range transformers.SortedKeys(hasMany) {
+		relation, _ := hasMany[name].(map[string]any)
+		childName, _ := relation["table"].(string)
+		fk, _ := relation["fk"].(string)
+
+		if childName == "" || fk == "" {
+			continue
+		}
+
+		child := s.entity(childName)
+		child.Add(&Column{Name: fk, Type: TypeBigint, Index: true})
+
+		// nested item definition, eg: "items":[{"name":"max:255"}] with has_many.items
+		nested := map[string]any{"has_many": relation["has_many"]}
+		if items, ok := transformer[name].([]any); ok && len(items) > 0 {
+			if fields, ok := items[0].(map[string]any); ok {
+				for key, value := range fields {
+					nested[key] = value
+				}
+			}
+		}
+
+		s.addRequest(child, nested, create, pivots)
+	}
+
+	for _, name := range transformers.SortedKeys(manyToMany) {
+		relation, _ := manyToMany[name].(map[string]any)
+		pivotName, _ := relation["table"].(string)
+		fk1, fk2 := queries.PivotKeys(relation)
+
+		if pivotName == "" || fk1 == "" || fk2 == "" {
+			continue
+		}
+
+		pivot := s.table(pivotName)
+		pivot.Add(&Column{Name: fk1, Type: TypeBigint, Index: true})
+		pivot.Add(&Column{Name: fk2, Type: TypeBigint, Index: true})
+		pivots[pivotName] = append(pivots[pivotName], fk1, fk2)
+	}
+}
+
+func (s *Schema) addResponse(table *Table, transformer map[string]any, pivots map[string][]string) {
+	filterable, _ := transformer["filterable"].(map[string]any)
+
+	for _, key := range transformers.SortedKeys(transformer) {
+		if transformers.ReservedKeys[key] {
+			continue
+		}
+
+		filterType, _ := filterable[key].(string)
+		table.AddIfMissing(ColumnFromFilter(key, filterType))
+	}
+
+	belongsTo, _ := transformer["belongs_to"].(map[string]any)
+	for _, name := range transformers.SortedKeys(belongsTo) {
+		relation, _ := belongsTo[name].(map[string]any)
+		if fk, _ := relation["fk"].(string); fk != "" {
+			table.AddIfMissing(&Column{Name: fk, Type: TypeBigint, Nullable: true, Index: true})
+		}
+	}
+
+	hasMany, _ := transformer["has_many"].(map[string]any)
+	for _, name := range transformers.SortedKeys(hasMany) {
+		relation, _ := hasMany[name].(map[string]any)
+		childName, _ := relation["table"].(string)
+		fk, _ := relation["fk"].(string)
+
+		if childName == "" || fk == "" {
+			continue
+		}
+
+		child := s.entity(childName)
+		child.AddIfMissing(&Column{Name: fk, Type: TypeBigint, Index: true})
+		for _, column := range queries.Columns(relation["columns"]) {
+			child.AddIfMissing(ColumnFromFilter(column, ""))
+		}
+	}
+
+	manyToMany, _ := transformer["many_to_many"].(map[string]any)
+	for _, name := range transformers.SortedKeys(manyToMany) {
+		relation, _ := manyToMany[name].(map[string]any)
+		pivotName, _ := relation["table"].(string)
+		fk1, fk2 := queries.PivotKeys(relation)
+
+		if pivotName == "" || fk1 == "" || fk2 == "" {
+			continue
+		}
+
+		pivot := s.table(pivotName)
+		pivot.AddIfMissing(&Column{Name: fk1, Type: TypeBigint, Index: true})
+		pivot.AddIfMissing(&Column{Name: fk2, Type: TypeBigint, Index: true})
+		pivots[pivotName] = append(pivots[pivotName], fk1, fk2)
+	}
+}
+
+// Add registers a column, merging it with an existing definition of the same name.
+func (t *Table) Add(column *Column) {
+	existing, ok := t.columns[column.Name]
+	if !ok {
+		t.columns[column.Name] = column
+		t.Columns = append(t.Columns, column)
+		return
+	}
+
+	if existing.Type == TypeText && column.Type != TypeText {
+		existing.Type = column.Type
+	}
+
+	if column.Size > existing.Size {
+		existing.Size = column.Size
+	}
+
+	existing.Nullable = existing.Nullable && column.Nullable
+	existing.Index = existing.Index || column.Index
+}
+
+// AddIfMissing registers a column only when no definition exists yet.
+func (t *Table) AddIfMissing(column *Column) {
+	if _, ok := t.columns[column.Name]; !ok {
+		t.Add(column)
+	}
+}
+
+// ColumnFromRule guesses the column of a request field from its validation rule, eg: "required|max:255".
+func ColumnFromRule(name string, rule string, create bool) *Column {
+	column := &Column{Name: name, Type: guessType(name), Nullable: true}
+
+	for _, part := range strings.Split(rule, "|") {
+		ruleName, arg, _ := strings.Cut(part, ":")
+
+		switch ruleName {
+		case "required":
+			column.Nullable = !create
+		case "number":
+			column.Type = TypeBigint
+		case "max":
+			if size, err := strconv.Atoi(arg); err == nil && column.Type == TypeText {
+				column.Type = TypeString
+				column.Size = size
+			}
+		}
+	}
+
+	return column
+}
+
+// ColumnFromFilter guesses the column of a response field from its filterable type.
+func ColumnFromFilter(name string, filterType string) *Column {
+	column := &Column{Name: name, Type: guessType(name), Nullable: true}
+
+	switch filterType {
+	case "int":
+		column.Type = TypeBigint
+	case "timestamp":
+		column.Type = TypeTimestamp
+	}
+
+	return column
+}
+
+func guessType(name string) string {
+	switch {
+	case strings.HasSuffix(name, "_id"):
+		return TypeBigint
+	case strings.HasSuffix(name, "_at"):
+		return TypeTimestamp
+	}
+
+	return TypeText
+}
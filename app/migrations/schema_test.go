@@ -0,0 +1,118 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/62teknologi/62whale/app/transformers"
+)
+
+// fixtures are the transformers of a posts catalog, its tags and categories.
+var fixtures = map[string]string{
+	"request/posts/create.json": `{
+		"owner":"user_id",
+		"title":"required|max:120",
+		"body":"",
+		"status_id":"number",
+		"tags":[{"name":"max:40"}],
+		"has_many":{"tags":{"table":"post_tags","fk":"post_id"}},
+		"categories":[""],
+		"many_to_many":{"categories":{"table":"post_categories","fk_1":"post_id","fk_2":"category_id"}}
+	}`,
+	"request/posts/policy.json": `{"operations":{"write":["author"]},"fields":{"status_id":["moderator"]}}`,
+	"response/posts/find.json": `{
+		"id":"",
+		"title":"",
+		"published_at":"",
+		"belongs_to":{"author":{"table":"users","fk":"author_id","columns":["name"]}},
+		"filterable":{"published_at":"timestamp"}
+	}`,
+}
+
+// loadFixtures registers the fixture transformers.
+func loadFixtures(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range fixtures {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := transformers.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func columnNames(table *Table) []string {
+	names := []string{}
+	for _, column := range table.Columns {
+		names = append(names, column.Name)
+	}
+
+	return names
+}
+
+func TestDerive(t *testing.T) {
+	loadFixtures(t)
+
+	schema := Derive()
+
+	if names := schema.Names(); !reflect.DeepEqual(names, []string{"post_categories", "post_tags", "posts"}) {
+		t.Fatalf("expected the tables of posts, its tags and categories, got %v", names)
+	}
+
+	tests := []struct {
+		table   string
+		columns []string
+	}{
+		{
+			table:   "posts",
+			columns: []string{"id", "version", "created_at", "updated_at", "deleted_at", "slug", "body", "status_id", "title", "user_id", "published_at", "author_id"},
+		},
+		{
+			table:   "post_tags",
+			columns: []string{"id", "version", "created_at", "updated_at", "deleted_at", "post_id", "name"},
+		},
+		{
+			table:   "post_categories",
+			columns: []string{"id", "post_id", "category_id"},
+		},
+	}
+
+	for _, test := range tests {
+		if columns := columnNames(schema.Tables[test.table]); !reflect.DeepEqual(columns, test.columns) {
+			t.Errorf("%s: expected the columns %v, got %v", test.table, test.columns, columns)
+		}
+	}
+
+	posts := schema.Tables["posts"].columns
+	expected := map[string]Column{
+		"title":        {Name: "title", Type: TypeString, Size: 120},
+		"body":         {Name: "body", Type: TypeText, Nullable: true},
+		"status_id":    {Name: "status_id", Type: TypeBigint, Nullable: true},
+		"user_id":      {Name: "user_id", Type: TypeBigint, Nullable: true, Index: true},
+		"published_at": {Name: "published_at", Type: TypeTimestamp, Nullable: true},
+		"author_id":    {Name: "author_id", Type: TypeBigint, Nullable: true, Index: true},
+		"version":      {Name: "version", Type: TypeBigint, Default: "0"},
+	}
+
+	for name, column := range expected {
+		if *posts[name] != column {
+			t.Errorf("posts.%s: expected %+v, got %+v", name, column, *posts[name])
+		}
+	}
+
+	if tag := *schema.Tables["post_tags"].columns["name"]; tag != (Column{Name: "name", Type: TypeString, Size: 40, Nullable: true}) {
+		t.Errorf("post_tags.name: expected a nullable VARCHAR(40), got %+v", tag)
+	}
+}
@@ -0,0 +1,493 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/catalogs"
+	"github.com/62teknologi/62whale/app/queries"
+	"github.com/62teknologi/62whale/app/transformers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SwaggerUI is the page rendering the spec served at /api/docs/openapi.json.
+//
+//go:embed swagger.html
+var SwaggerUI []byte
+
+// swaggerAssets are the stylesheet and bundle of swagger-ui-dist 5.18.2, vendored so the page needs no CDN.
+//
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var swaggerAssets embed.FS
+
+// SwaggerAssets serves the files loaded by SwaggerUI, eg: swagger-ui.css.
+var SwaggerAssets, _ = fs.Sub(swaggerAssets, "swagger-ui")
+
+// operations names what a catalog route does, keyed by "METHOD suffix".
+var operations = map[string]string{
+	"GET /:id":          "find",
+	"GET /slug/:slug":   "find",
+	"GET ":              "find_all",
+	"POST ":             "create",
+	"POST /bulk":        "bulk_create",
+	"PUT /:id":          "update",
+	"PATCH /:id":        "patch",
+	"DELETE /:id":       "delete",
+	"DELETE ":           "delete_by_query",
+	"POST /:id/restore": "restore",
+	"DELETE /:id/force": "force_delete",
+}
+
+// Build generates an OpenAPI 3 document from the registered routes and the transformer registry.
+func Build(routes gin.RoutesInfo) map[string]any {
+	paths := map[string]any{}
+	tables := catalogs.Names()
+
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, "/api/docs") {
+			continue
+		}
+
+		prefix, suffix, ok := strings.Cut(route.Path, "/:table")
+		if !ok {
+			addOperation(paths, route.Method, toOpenAPIPath(route.Path), map[string]any{
+				"summary":   route.Method + " " + route.Path,
+				"responses": map[string]any{"200": envelopeResponse(map[string]any{})},
+			})
+			continue
+		}
+
+		kind := prefix[strings.LastIndex(prefix, "/")+1:]
+		operation := operations[route.Method+" "+suffix]
+
+		for _, table := range tables {
+			param, ok := paramOf(kind, table)
+			if !ok || !catalogs.Allowed(table, operation) {
+				continue
+			}
+
+			path := toOpenAPIPath(prefix + "/" + param + suffix)
+			addOperation(paths, route.Method, path, buildOperation(kind, table, operation, suffix))
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "62whale",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Envelope":          envelopeSchema(false),
+				"PaginatedEnvelope": envelopeSchema(true),
+			},
+		},
+	}
+}
+
+// paramOf returns the :table value addressing table through a route kind,
+// eg: "product" for product_comments through the comment routes.
+func paramOf(kind string, table string) (string, bool) {
+	if kind == "catalog" {
+		return table, true
+	}
+
+	suffix := "_" + utils.Pluralize.Plural(kind)
+	if strings.HasSuffix(table, suffix) {
+		return strings.TrimSuffix(table, suffix), true
+	}
+
+	return "", false
+}
+
+func addOperation(paths map[string]any, method string, path string, operation map[string]any) {
+	item, ok := paths[path].(map[string]any)
+	if !ok {
+		item = map[string]any{}
+		paths[path] = item
+	}
+
+	item[strings.ToLower(method)] = operation
+}
+
+func toOpenAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimPrefix(segment, ":") + "}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func buildOperation(kind string, table string, operation string, suffix string) map[string]any {
+	result := map[string]any{
+		"tags":        []string{table},
+		"operationId": operation + "_" + kind + "_" + table,
+		"summary":     strings.ReplaceAll(operation, "_", " ") + " " + table,
+		"parameters":  pathParameters(suffix),
+	}
+
+	response, _ := transformers.Get("response/" + table + "/find")
+
+	switch operation {
+	case "find":
+		result["responses"] = map[string]any{"200": envelopeResponse(ResponseSchema(response)), "400": errorResponse()}
+		result["parameters"] = append(result["parameters"].([]map[string]any), trashedParameters()...)
+		result["parameters"] = append(result["parameters"].([]map[string]any), fieldsParameters()...)
+	case "find_all":
+		result["responses"] = map[string]any{"200": paginatedResponse(ResponseSchema(response))}
+		result["parameters"] = append(result["parameters"].([]map[string]any), listParameters(kind, response)...)
+	case "create", "update":
+		request, err := transformers.Get("request/" + table + "/" + operation)
+		if err == nil {
+			result["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": RequestSchema(request, operation == "create")},
+				},
+			}
+		}
+		result["responses"] = map[string]any{"200": envelopeResponse(ResponseSchema(response)), "400": errorResponse()}
+	case "patch":
+		request, err := transformers.Get("request/" + table + "/update")
+		if err == nil {
+			result["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/merge-patch+json": map[string]any{"schema": RequestSchema(request, false)},
+					"application/json":             map[string]any{"schema": RequestSchema(request, false)},
+				},
+			}
+		}
+		result["responses"] = map[string]any{"200": envelopeResponse(ResponseSchema(response)), "400": errorResponse()}
+	case "bulk_create":
+		request, err := transformers.Get("request/" + table + "/create")
+		if err == nil {
+			result["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": map[string]any{"type": "array", "items": RequestSchema(request, true)}},
+				},
+			}
+		}
+		result["parameters"] = append(result["parameters"].([]map[string]any),
+			queryParameter("mode", "atomic creates every record or none, partial keeps the valid ones", map[string]any{"type": "string", "enum": []string{"atomic", "partial"}, "default": "atomic"}),
+		)
+		result["responses"] = map[string]any{
+			"200": envelopeResponse(bulkResultSchema()),
+			"207": envelopeResponse(bulkResultSchema()),
+			"400": errorResponse(),
+		}
+	case "delete_by_query":
+		// the rows are matched by the filters of the delete transformer, not by those of the response
+		request, _ := transformers.Get("request/" + table + "/delete")
+		result["parameters"] = append(result["parameters"].([]map[string]any), filterParameters(request)...)
+		result["responses"] = map[string]any{"200": envelopeResponse(map[string]any{}), "400": errorResponse()}
+	default:
+		result["responses"] = map[string]any{"200": envelopeResponse(map[string]any{}), "400": errorResponse()}
+	}
+
+	switch operation {
+	case "update", "patch", "delete":
+		result["parameters"] = append(result["parameters"].([]map[string]any), map[string]any{
+			"name":        "If-Match",
+			"in":          "header",
+			"description": "ETag returned by find, the write is refused when the record changed since",
+			"schema":      map[string]any{"type": "string"},
+		})
+		result["responses"].(map[string]any)["412"] = errorResponse()
+	}
+
+	return result
+}
+
+func bulkResultSchema() map[string]any {
+	return map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"index":  map[string]any{"type": "integer"},
+				"status": map[string]any{"type": "string", "enum": []string{"created", "invalid", "failed", "skipped", "rolled_back"}},
+				"errors": map[string]any{},
+				"id":     map[string]any{},
+			},
+		},
+	}
+}
+
+func pathParameters(suffix string) []map[string]any {
+	parameters := []map[string]any{}
+
+	for _, segment := range strings.Split(suffix, "/") {
+		if strings.HasPrefix(segment, ":") {
+			parameters = append(parameters, map[string]any{
+				"name":     strings.TrimPrefix(segment, ":"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+	}
+
+	return parameters
+}
+
+func queryParameter(name string, description string, schema map[string]any) map[string]any {
+	return map[string]any{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+func trashedParameters() []map[string]any {
+	return []map[string]any{
+		queryParameter("with_trashed", "include soft deleted data", map[string]any{"type": "boolean"}),
+		queryParameter("only_trashed", "return soft deleted data only", map[string]any{"type": "boolean"}),
+	}
+}
+
+func fieldsParameters() []map[string]any {
+	return []map[string]any{
+		queryParameter("fields", "comma separated fields and relation fields to return, eg: id,name,category.name", map[string]any{"type": "string"}),
+		queryParameter("include", "comma separated relations to load, eg: brand,items.attributes", map[string]any{"type": "string"}),
+		queryParameter("exclude", "comma separated relations not to load", map[string]any{"type": "string"}),
+	}
+}
+
+func listParameters(kind string, response map[string]any) []map[string]any {
+	parameters := []map[string]any{
+		queryParameter("page", "page number", map[string]any{"type": "integer", "default": 1}),
+		queryParameter("per_page", "data per page", map[string]any{"type": "integer", "default": 30}),
+		queryParameter("order", "order by one or multiple fields, eg: name+asc or brand.name+desc", map[string]any{"type": "string"}),
+		queryParameter("cursor", "page with a cursor instead of page, empty for the first page", map[string]any{"type": "string"}),
+		queryParameter("limit", "data per cursor page", map[string]any{"type": "integer", "default": 30, "maximum": 1000}),
+		queryParameter("with_total", "count the data of a cursor page", map[string]any{"type": "boolean"}),
+	}
+
+	if _, ok := response["searchable"]; ok {
+		parameters = append(parameters, queryParameter("search", "search in "+strings.Join(queries.Columns(response["searchable"]), ", "), map[string]any{"type": "string"}))
+	}
+
+	if kind == "comment" || kind == "category" {
+		parameters = append(parameters,
+			queryParameter("include_childs", "attach the children recursively", map[string]any{"type": "boolean"}),
+			queryParameter("depth", "levels of children to attach, unlimited by default", map[string]any{"type": "integer", "minimum": 1}),
+		)
+	}
+
+	parameters = append(parameters, trashedParameters()...)
+	parameters = append(parameters, fieldsParameters()...)
+
+	return append(parameters, filterParameters(response)...)
+}
+
+func filterParameters(response map[string]any) []map[string]any {
+	filterable, _ := response["filterable"].(map[string]any)
+	parameters := []map[string]any{}
+
+	for _, key := range transformers.SortedKeys(filterable) {
+		filterType, _ := filterable[key].(string)
+		schema := filterSchema(filterType)
+
+		for _, operator := range queries.FilterOperators[filterType] {
+			switch operator {
+			case "eq":
+				parameters = append(parameters, queryParameter(key, "filter by "+key, schema))
+			case "in":
+				parameters = append(parameters, queryParameter(key+"[]", "filter by any of "+key, map[string]any{"type": "array", "items": schema}))
+			case "null":
+				parameters = append(parameters, queryParameter(key+"[null]", "filter on "+key+" being null or not", map[string]any{"type": "boolean"}))
+			case "between":
+				parameters = append(parameters, queryParameter(key+"[between]", "filter by "+key+" between two comma separated values", map[string]any{"type": "string"}))
+			case "like":
+				parameters = append(parameters, queryParameter(key+"[like]", "filter by "+key+" containing the value, or matching it when it has a %", map[string]any{"type": "string"}))
+			default:
+				parameters = append(parameters, queryParameter(key+"["+operator+"]", "filter by "+key+" "+operator, schema))
+			}
+		}
+	}
+
+	return parameters
+}
+
+func filterSchema(filterType string) map[string]any {
+	switch filterType {
+	case "int":
+		return map[string]any{"type": "integer"}
+	case "timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	return map[string]any{"type": "string"}
+}
+
+// RequestSchema converts a request transformer into a JSON schema, validation rules become constraints.
+func RequestSchema(transformer map[string]any, create bool) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	for _, key := range transformers.SortedKeys(transformer) {
+		if transformers.ReservedKeys[key] {
+			continue
+		}
+
+		switch value := transformer[key].(type) {
+		case string:
+			schema, isRequired := ruleSchema(value)
+			properties[key] = schema
+			if isRequired && create {
+				required = append(required, key)
+			}
+		case []any:
+			items := map[string]any{}
+			if len(value) > 0 {
+				if nested, ok := value[0].(map[string]any); ok {
+					items = RequestSchema(nested, create)
+				}
+			}
+			properties[key] = map[string]any{"type": "array", "items": items}
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func ruleSchema(rule string) (map[string]any, bool) {
+	schema := map[string]any{"type": "string"}
+	required := false
+	limits := map[string]int{}
+
+	for _, part := range strings.Split(rule, "|") {
+		name, arg, _ := strings.Cut(part, ":")
+
+		switch name {
+		case "required":
+			required = true
+		case "number":
+			schema["type"] = "number"
+		case "email":
+			schema["format"] = "email"
+		case "min", "max":
+			if limit, err := strconv.Atoi(arg); err == nil {
+				limits[name] = limit
+			}
+		}
+	}
+
+	for name, limit := range limits {
+		if schema["type"] == "number" {
+			schema[map[string]string{"min": "minimum", "max": "maximum"}[name]] = limit
+		} else {
+			schema[map[string]string{"min": "minLength", "max": "maxLength"}[name]] = limit
+		}
+	}
+
+	return schema, required
+}
+
+// ResponseSchema converts a response transformer into a JSON schema.
+func ResponseSchema(transformer map[string]any) map[string]any {
+	properties := map[string]any{}
+	filterable, _ := transformer["filterable"].(map[string]any)
+
+	for _, key := range transformers.SortedKeys(transformer) {
+		if transformers.ReservedKeys[key] {
+			continue
+		}
+
+		if filterType, ok := filterable[key].(string); ok {
+			properties[key] = filterSchema(filterType)
+		} else {
+			properties[key] = map[string]any{}
+		}
+	}
+
+	belongsTo, _ := transformer["belongs_to"].(map[string]any)
+	for name, relation := range belongsTo {
+		properties[name] = relationSchema(relation)
+	}
+
+	for _, kind := range []string{"has_many", "many_to_many"} {
+		relations, _ := transformer[kind].(map[string]any)
+		for name, relation := range relations {
+			properties[name] = map[string]any{"type": "array", "items": relationSchema(relation)}
+		}
+	}
+
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func relationSchema(relation any) map[string]any {
+	options, _ := relation.(map[string]any)
+	properties := map[string]any{}
+
+	for _, column := range queries.Columns(options["columns"]) {
+		properties[column] = map[string]any{}
+	}
+
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func envelopeSchema(paginated bool) map[string]any {
+	properties := map[string]any{
+		"status":  map[string]any{"type": "string", "enum": []string{"success", "error"}},
+		"message": map[string]any{"type": "string"},
+		"data":    map[string]any{},
+	}
+
+	if paginated {
+		properties["pagination"] = map[string]any{"type": "object", "additionalProperties": true}
+		properties["filter"] = map[string]any{"type": "object", "additionalProperties": true}
+		properties["search"] = map[string]any{}
+		properties["summary"] = map[string]any{}
+	}
+
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func withData(envelope string, data map[string]any) map[string]any {
+	return map[string]any{
+		"allOf": []map[string]any{
+			{"$ref": "#/components/schemas/" + envelope},
+			{"type": "object", "properties": map[string]any{"data": data}},
+		},
+	}
+}
+
+func envelopeResponse(data map[string]any) map[string]any {
+	return map[string]any{
+		"description": "success",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": withData("Envelope", data)},
+		},
+	}
+}
+
+func paginatedResponse(data map[string]any) map[string]any {
+	return map[string]any{
+		"description": "success",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": withData("PaginatedEnvelope", map[string]any{"type": "array", "items": data})},
+		},
+	}
+}
+
+func errorResponse() map[string]any {
+	return map[string]any{
+		"description": "error",
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Envelope"}},
+		},
+	}
+}
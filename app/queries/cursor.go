@@ -0,0 +1,276 @@
+package queries
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/62teknologi/62whale/app/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCursorLimit = 30
+	maxCursorLimit     = 1000
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+func init() {
+	// cursor values are scanned from the database, timestamps included
+	gob.Register(time.Time{})
+}
+
+// Cursor pages a listing on its order columns plus id instead of an offset, so a page costs the same
+// wherever it is and rows written meanwhile don't shift the next pages.
+type Cursor struct {
+	table    string
+	columns  []string
+	desc     []bool
+	limit    int
+	values   []any
+	backward bool
+	total    bool
+}
+
+type cursorToken struct {
+	Order    string
+	Values   []any
+	Backward bool
+}
+
+// NewCursor reads the "cursor" and "limit" query parameters, it returns nil when the listing is paged by offset.
+// The order comes from the "order" parameters, eg: "order=name+asc", restricted to the columns of table.
+func NewCursor(ctx *gin.Context, db *gorm.DB, table string) (*Cursor, error) {
+	token, hasCursor := ctx.GetQuery("cursor")
+	limit, hasLimit := ctx.GetQuery("limit")
+
+	if !hasCursor && !hasLimit {
+		return nil, nil
+	}
+
+	cursor := &Cursor{table: table, limit: defaultCursorLimit, total: Flag(ctx, "with_total")}
+
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxCursorLimit {
+			return nil, errors.New("limit must be a number between 1 and " + strconv.Itoa(maxCursorLimit))
+		}
+		cursor.limit = value
+	}
+
+	orders, err := parseOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if !orderPattern.MatchString(order.field) || !database.HasColumn(db, table, order.field) {
+			return nil, errors.New("cannot order a cursor page by " + order.field)
+		}
+
+		cursor.columns = append(cursor.columns, order.field)
+		cursor.desc = append(cursor.desc, order.desc)
+
+		// id is unique, the columns after it can't change the order
+		if order.field == "id" {
+			break
+		}
+	}
+
+	if len(cursor.columns) == 0 || cursor.columns[len(cursor.columns)-1] != "id" {
+		cursor.columns = append(cursor.columns, "id")
+		cursor.desc = append(cursor.desc, false)
+	}
+
+	if token != "" {
+		decoded, err := decodeCursor(token)
+		if err != nil || decoded.Order != cursor.order() || len(decoded.Values) != len(cursor.columns) {
+			return nil, errInvalidCursor
+		}
+
+		cursor.values = decoded.Values
+		cursor.backward = decoded.Backward
+	}
+
+	return cursor, nil
+}
+
+// Apply orders and limits the query past the cursor, it counts the matching rows beforehand only when
+// "with_total" is requested. The order columns are added to the selected columns, the returned pagination
+// is completed by Page.
+func (c *Cursor) Apply(query *gorm.DB, columns *[]string) (map[string]any, error) {
+	pagination := map[string]any{"limit": c.limit, "next_cursor": nil, "prev_cursor": nil}
+
+	if c.total {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		pagination["total"] = total
+	}
+
+	if c.values != nil {
+		where, args := c.condition(query.Dialector.Name())
+		query.Where(where, args...)
+	}
+
+	if !contains(*columns, c.table+".*") {
+		for _, column := range c.columns {
+			*columns = AppendUnique(*columns, c.table+"."+column)
+		}
+	}
+
+	for i, column := range c.columns {
+		column = c.table + "." + column
+		direction := " ASC"
+
+		// a previous page is read backward from the cursor then reversed by Page
+		if c.desc[i] != c.backward {
+			direction = " DESC"
+		}
+
+		// NULLs sort after every value whatever the database, as condition expects
+		if c.columns[i] != "id" {
+			query.Order(column + " IS NULL" + direction)
+		}
+
+		query.Order(column + direction)
+	}
+
+	// the extra row tells whether another page follows
+	query.Limit(c.limit + 1)
+
+	return pagination, nil
+}
+
+// Page trims the rows fetched by Apply to the requested page and sets the cursors of the neighbour pages.
+func (c *Cursor) Page(values []map[string]any, pagination map[string]any) []map[string]any {
+	more := len(values) > c.limit
+	if more {
+		values = values[:c.limit]
+	}
+
+	if c.backward {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	if len(values) == 0 {
+		return values
+	}
+
+	// going backward the cursor row itself follows the page, going forward it precedes it
+	if (!c.backward && more) || (c.backward && c.values != nil) {
+		pagination["next_cursor"] = c.encode(values[len(values)-1], false)
+	}
+
+	if (c.backward && more) || (!c.backward && c.values != nil) {
+		pagination["prev_cursor"] = c.encode(values[0], true)
+	}
+
+	return values
+}
+
+// condition matches the rows after the cursor in the order of the page, eg: for "name asc, id asc"
+// (name > ? OR name IS NULL) OR (name = ? AND id > ?). A NULL is placed after every value.
+func (c *Cursor) condition(driver string) (string, []any) {
+	ors := []string{}
+	args := []any{}
+
+	for i := range c.columns {
+		ands := []string{}
+
+		for j := 0; j < i; j++ {
+			where, values := c.equal(driver, j)
+			ands = append(ands, where)
+			args = append(args, values...)
+		}
+
+		where, values := c.after(driver, i)
+		ands = append(ands, where)
+		args = append(args, values...)
+
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// equal matches the rows holding the cursor value of the i-th column.
+func (c *Cursor) equal(driver string, i int) (string, []any) {
+	column := c.table + "." + c.columns[i]
+
+	if c.values[i] == nil {
+		return column + " IS NULL", nil
+	}
+
+	return comparison(driver, column, "=", c.values[i]), []any{c.values[i]}
+}
+
+// after matches the rows following the cursor value of the i-th column in the order of the page.
+func (c *Cursor) after(driver string, i int) (string, []any) {
+	column := c.table + "." + c.columns[i]
+	descending := c.desc[i] != c.backward
+
+	switch {
+	case c.values[i] == nil && descending:
+		return column + " IS NOT NULL", nil
+	case c.values[i] == nil:
+		return "1 = 0", nil
+	case descending:
+		return comparison(driver, column, "<", c.values[i]), []any{c.values[i]}
+	}
+
+	return "(" + comparison(driver, column, ">", c.values[i]) + " OR " + column + " IS NULL)", []any{c.values[i]}
+}
+
+// order identifies the order a cursor was issued for.
+func (c *Cursor) order() string {
+	parts := make([]string, len(c.columns))
+
+	for i, column := range c.columns {
+		if c.desc[i] {
+			parts[i] = column + " desc"
+		} else {
+			parts[i] = column + " asc"
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (c *Cursor) encode(row map[string]any, backward bool) string {
+	token := cursorToken{Order: c.order(), Backward: backward}
+
+	for _, column := range c.columns {
+		token.Values = append(token.Values, row[column])
+	}
+
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(token); err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer.Bytes())
+}
+
+func decodeCursor(value string) (cursorToken, error) {
+	token := cursorToken{}
+
+	content, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return token, err
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(content)).Decode(&token)
+
+	return token, err
+}
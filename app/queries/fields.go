@@ -0,0 +1,131 @@
+package queries
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/transformers"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// relationKinds are the transformer keys declaring relations a field may reach, eg: "category.name".
+var relationKinds = []string{"belongs_to", "has_many", "many_to_many"}
+
+// SetFields applies the "fields" query parameter, eg: "fields=id,name,category.name", to a response transformer.
+// Keys and relations which aren't requested are dropped, a relation requested with sub-fields keeps those columns only.
+// columns is narrowed to the requested fields of table plus the ones the response relies on: id, the ETag columns
+// and the keys of belongs_to relations. id is always part of the response.
+func SetFields(ctx *gin.Context, db *gorm.DB, table string, transformer map[string]any, columns *[]string) error {
+	fields := ctx.Query("fields")
+	if fields == "" {
+		return nil
+	}
+
+	keys := []string{"id"}
+	relations := map[string][]string{}
+
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		name, column, nested := strings.Cut(field, ".")
+
+		kind, options := relationOf(transformer, name)
+		if options == nil {
+			if _, ok := transformer[name].(string); !ok || nested || transformers.ReservedKeys[name] {
+				return errors.New("unknown field " + field)
+			}
+
+			keys = AppendUnique(keys, name)
+			continue
+		}
+
+		if _, ok := relations[name]; !ok {
+			relations[name] = []string{}
+		}
+
+		if !nested {
+			continue
+		}
+
+		if !hasRelatedColumn(db, kind, options, column) {
+			return errors.New("unknown field " + field)
+		}
+
+		relations[name] = AppendUnique(relations[name], column)
+	}
+
+	for key, value := range transformer {
+		if _, ok := value.(string); ok && !transformers.ReservedKeys[key] && !contains(keys, key) {
+			delete(transformer, key)
+		}
+	}
+
+	selected := append([]string{}, keys...)
+
+	for _, kind := range relationKinds {
+		declared, _ := transformer[kind].(map[string]any)
+
+		for name, relation := range declared {
+			requested, ok := relations[name]
+			if !ok {
+				delete(declared, name)
+				continue
+			}
+
+			options, _ := relation.(map[string]any)
+			if len(requested) > 0 {
+				options["columns"] = requested
+			}
+
+			if fk, _ := options["fk"].(string); kind == "belongs_to" && fk != "" {
+				selected = AppendUnique(selected, fk)
+			}
+		}
+
+		if len(declared) == 0 {
+			delete(transformer, kind)
+		}
+	}
+
+	// the summary may be computed from any column
+	if _, ok := transformer["summary"]; ok {
+		return nil
+	}
+
+	// the ETag is computed from the revision columns whatever the response shows
+	for _, column := range database.RevisionColumns(db, table) {
+		selected = AppendUnique(selected, column)
+	}
+
+	*columns = QualifyColumns(table, selected)
+
+	return nil
+}
+
+// relationOf returns the kind and options of the relation declared under name.
+func relationOf(transformer map[string]any, name string) (string, map[string]any) {
+	for _, kind := range relationKinds {
+		declared, _ := transformer[kind].(map[string]any)
+		if options, ok := declared[name].(map[string]any); ok {
+			return kind, options
+		}
+	}
+
+	return "", nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,199 @@
+package queries
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FilterOperators lists the operators accepted by each "filterable" type, eg: "price[gte]=10".
+// "eq" is sent as "price=10" and "in" as "price[]=10&price[]=20".
+var FilterOperators = map[string][]string{
+	"int":       {"eq", "ne", "gt", "gte", "lt", "lte", "between", "in", "null"},
+	"string":    {"eq", "ne", "like", "in", "null"},
+	"timestamp": {"eq", "ne", "gt", "gte", "lt", "lte", "between", "null"},
+}
+
+var filterComparisons = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// SetFilterByQuery filters query on the "filterable" fields of the transformer sent as query parameters.
+// A dotted field filters on a column of a relation, eg: "category.slug=shoes" or "categories.id[]=3".
+// It returns the applied filters normalized by field and operator, eg: {"price": {"gte": 10}}, empty when the query
+// isn't filtered.
+func SetFilterByQuery(query *gorm.DB, table string, transformer map[string]any, ctx *gin.Context) (map[string]any, error) {
+	filterable, _ := transformer["filterable"].(map[string]any)
+	values := ctx.Request.URL.Query()
+	filter := map[string]any{}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field, operator := name, "eq"
+		if open := strings.Index(name, "["); open > 0 && strings.HasSuffix(name, "]") {
+			field, operator = name[:open], name[open+1:len(name)-1]
+			if operator == "" {
+				operator = "in"
+			}
+		}
+
+		filterType, ok := filterable[field].(string)
+		if !ok {
+			continue
+		}
+
+		if !allowsOperator(filterType, operator) {
+			return nil, errors.New(field + " can't be filtered with " + operator + ", expected one of " + strings.Join(FilterOperators[filterType], ", "))
+		}
+
+		if operator != "in" && values.Get(name) == "" {
+			continue
+		}
+
+		value, err := filterValue(filterType, operator, values[name])
+		if err != nil {
+			return nil, errors.New(name + " " + err.Error())
+		}
+
+		if list, ok := value.([]any); ok && len(list) == 0 {
+			continue
+		}
+
+		if name, column, related := strings.Cut(field, "."); related {
+			kind, options := relationOf(transformer, name)
+			if options == nil {
+				continue
+			}
+
+			whereRelated(query, table, kind, name, options, column, operator, value)
+		} else {
+			where, args := filterCondition(query.Dialector.Name(), table+"."+field, operator, value)
+			query.Where(where, args...)
+		}
+
+		operators, _ := filter[field].(map[string]any)
+		if operators == nil {
+			operators = map[string]any{}
+			filter[field] = operators
+		}
+		operators[operator] = value
+	}
+
+	return filter, nil
+}
+
+func allowsOperator(filterType string, operator string) bool {
+	for _, allowed := range FilterOperators[filterType] {
+		if allowed == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterValue parses the raw values of an operator, "between" takes two comma separated values.
+func filterValue(filterType string, operator string, raw []string) (any, error) {
+	switch operator {
+	case "null":
+		value, err := strconv.ParseBool(raw[0])
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return value, nil
+	case "like":
+		if strings.Contains(raw[0], "%") {
+			return raw[0], nil
+		}
+		return "%" + raw[0] + "%", nil
+	case "in":
+		values := []any{}
+		for _, item := range raw {
+			if item == "" {
+				continue
+			}
+			value, err := parseFilter(filterType, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case "between":
+		bounds := strings.Split(raw[0], ",")
+		if len(bounds) != 2 {
+			return nil, errors.New("must be two comma separated values")
+		}
+		values := []any{}
+		for _, bound := range bounds {
+			value, err := parseFilter(filterType, strings.TrimSpace(bound))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+
+	return parseFilter(filterType, raw[0])
+}
+
+func parseFilter(filterType string, raw string) (any, error) {
+	switch filterType {
+	case "int":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return value, nil
+	case "timestamp":
+		for _, layout := range timestampLayouts {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, errors.New("must be a date, eg: 2023-01-31 or 2023-01-31T10:00:00Z")
+	}
+
+	return raw, nil
+}
+
+// filterCondition is the condition of an operator on column with its arguments.
+func filterCondition(driver string, column string, operator string, value any) (string, []any) {
+	switch operator {
+	case "null":
+		if value.(bool) {
+			return column + " IS NULL", nil
+		}
+		return column + " IS NOT NULL", nil
+	case "like":
+		return column + " LIKE ?", []any{value}
+	case "in":
+		return column + " IN ?", []any{value}
+	case "between":
+		bounds := value.([]any)
+		return comparison(driver, column, ">=", bounds[0]) + " AND " + comparison(driver, column, "<=", bounds[1]), bounds
+	}
+
+	return comparison(driver, column, filterComparisons[operator], value), []any{value}
+}
+
+// comparison compares column with a bound value, eg: "products.price >= ?".
+func comparison(driver string, column string, operator string, value any) string {
+	// sqlite stores timestamps as text in several formats, compare them as dates
+	if _, ok := value.(time.Time); ok && driver == "sqlite" {
+		return "julianday(" + column + ") " + operator + " julianday(?)"
+	}
+
+	return column + " " + operator + " ?"
+}
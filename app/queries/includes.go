@@ -0,0 +1,116 @@
+package queries
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// includePaths is a tree of relation names, eg: "items.attributes,brand" is {items: {attributes: {}}, brand: {}}.
+type includePaths map[string]includePaths
+
+// SetIncludes applies the "include" and "exclude" query parameters to the relations declared by a response transformer,
+// eg: "include=brand,items.attributes". Relations left out are removed from the transformer so they are never queried.
+// Every declared relation is loaded when neither parameter is sent.
+func SetIncludes(ctx *gin.Context, transformer map[string]any) error {
+	include, err := parseIncludes(transformer, ctx.Query("include"))
+	if err != nil {
+		return err
+	}
+
+	exclude, err := parseIncludes(transformer, ctx.Query("exclude"))
+	if err != nil {
+		return err
+	}
+
+	if include != nil {
+		keepRelations(transformer, include)
+	}
+
+	if exclude != nil {
+		dropRelations(transformer, exclude)
+	}
+
+	return nil
+}
+
+// parseIncludes reads a comma separated list of relation paths, every name must be declared at its level.
+func parseIncludes(transformer map[string]any, value string) (includePaths, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	paths := includePaths{}
+
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		declaration, node := transformer, paths
+
+		for _, name := range strings.Split(path, ".") {
+			_, options := relationOf(declaration, name)
+			if options == nil {
+				return nil, errors.New("unknown relation " + path)
+			}
+
+			if node[name] == nil {
+				node[name] = includePaths{}
+			}
+
+			declaration, node = options, node[name]
+		}
+	}
+
+	return paths, nil
+}
+
+// keepRelations removes the relations missing from paths, a relation listed without children loads none of its own.
+func keepRelations(declaration map[string]any, paths includePaths) {
+	for _, kind := range relationKinds {
+		relations, _ := declaration[kind].(map[string]any)
+
+		for name, relation := range relations {
+			children, ok := paths[name]
+			if !ok {
+				delete(relations, name)
+				continue
+			}
+
+			if options, ok := relation.(map[string]any); ok {
+				keepRelations(options, children)
+			}
+		}
+
+		if relations != nil && len(relations) == 0 {
+			delete(declaration, kind)
+		}
+	}
+}
+
+// dropRelations removes the relations ending a path, eg: "items.attributes" keeps items without their attributes.
+func dropRelations(declaration map[string]any, paths includePaths) {
+	for _, kind := range relationKinds {
+		relations, _ := declaration[kind].(map[string]any)
+
+		for name, relation := range relations {
+			children, ok := paths[name]
+			if !ok {
+				continue
+			}
+
+			if len(children) == 0 {
+				delete(relations, name)
+			} else if options, ok := relation.(map[string]any); ok {
+				dropRelations(options, children)
+			}
+		}
+
+		if relations != nil && len(relations) == 0 {
+			delete(declaration, kind)
+		}
+	}
+}
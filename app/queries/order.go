@@ -0,0 +1,88 @@
+package queries
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var orderPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type orderBy struct {
+	field string
+	desc  bool
+}
+
+// parseOrders reads the "order" parameters, eg: "order=name+asc" or "order[]=name+asc&order[]=created_at+desc".
+func parseOrders(ctx *gin.Context) ([]orderBy, error) {
+	values := ctx.QueryArray("order[]")
+	if order := ctx.Query("order"); order != "" {
+		values = append(values, order)
+	}
+
+	orders := []orderBy{}
+
+	for _, value := range values {
+		parts := strings.Fields(value)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, errors.New("cannot order by " + strconv.Quote(value))
+		}
+
+		order := orderBy{field: parts[0]}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				order.desc = true
+			default:
+				return nil, errors.New("cannot order by " + strconv.Quote(value))
+			}
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// SetOrderByQuery orders query by the "order" parameters on columns of table, a dotted field sorts on a column
+// of a belongs_to relation declared by the transformer through a join, eg: "order=brand.name+asc".
+func SetOrderByQuery(query *gorm.DB, table string, transformer map[string]any, ctx *gin.Context) error {
+	orders, err := parseOrders(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		column := ""
+
+		if name, field, related := strings.Cut(order.field, "."); related {
+			kind, options := relationOf(transformer, name)
+			if kind != "belongs_to" || !hasRelatedColumn(query, kind, options, field) {
+				return errors.New("cannot order by " + order.field)
+			}
+
+			column = joinBelongsTo(query, table, name, options) + "." + field
+		} else {
+			if !orderPattern.MatchString(order.field) || !database.HasColumn(query, table, order.field) {
+				return errors.New("cannot order by " + order.field)
+			}
+
+			column = table + "." + order.field
+		}
+
+		if order.desc {
+			query.Order(column + " DESC")
+		} else {
+			query.Order(column + " ASC")
+		}
+	}
+
+	return nil
+}
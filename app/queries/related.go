@@ -0,0 +1,96 @@
+package queries
+
+import (
+	"github.com/62teknologi/62whale/app/database"
+
+	"gorm.io/gorm"
+)
+
+// relatedAlias is the alias of the join reaching a belongs_to relation, eg: "related_brand".
+func relatedAlias(name string) string {
+	return "related_" + name
+}
+
+// joinBelongsTo left joins the table of a belongs_to relation, once per query, and returns its alias.
+func joinBelongsTo(query *gorm.DB, table string, name string, options map[string]any) string {
+	alias := relatedAlias(name)
+	key := "queries:join:" + alias
+
+	if _, ok := query.Get(key); ok {
+		return alias
+	}
+
+	relationTable, _ := options["table"].(string)
+	fk, _ := options["fk"].(string)
+	on := alias + ".id = " + table + "." + fk
+
+	if database.SoftDeletes(query, relationTable) {
+		on += " AND " + alias + "." + database.DeletedAt + " IS NULL"
+	}
+
+	query.Joins("LEFT JOIN " + relationTable + " " + alias + " ON " + on)
+	query.Set(key, true)
+
+	return alias
+}
+
+// whereRelated filters table on a column of a relation: through a join for belongs_to, otherwise
+// with an EXISTS subquery so a row matched by several related rows is returned once.
+func whereRelated(query *gorm.DB, table string, kind string, name string, options map[string]any, column string, operator string, value any) {
+	driver := query.Dialector.Name()
+	relationTable, _ := options["table"].(string)
+
+	switch kind {
+	case "belongs_to":
+		alias := joinBelongsTo(query, table, name, options)
+		where, args := filterCondition(driver, alias+"."+column, operator, value)
+		query.Where(where, args...)
+	case "has_many":
+		fk, _ := options["fk"].(string)
+		where, args := filterCondition(driver, relationTable+"."+column, operator, value)
+
+		if database.SoftDeletes(query, relationTable) {
+			where += " AND " + relationTable + "." + database.DeletedAt + " IS NULL"
+		}
+
+		query.Where("EXISTS (SELECT 1 FROM "+relationTable+" WHERE "+relationTable+"."+fk+" = "+table+".id AND "+where+")", args...)
+	case "many_to_many":
+		fk1, fk2 := PivotKeys(options)
+		ft, _ := options["ft"].(string)
+		from := relationTable
+		target := relationTable
+
+		if ft != "" {
+			from += " JOIN " + ft + " ON " + ft + ".id = " + relationTable + "." + fk2
+			target = ft
+		}
+
+		where, args := filterCondition(driver, target+"."+column, operator, value)
+
+		if ft != "" && database.SoftDeletes(query, ft) {
+			where += " AND " + ft + "." + database.DeletedAt + " IS NULL"
+		}
+
+		query.Where("EXISTS (SELECT 1 FROM "+from+" WHERE "+relationTable+"."+fk1+" = "+table+".id AND "+where+")", args...)
+	}
+}
+
+// relatedTable is the table holding the columns of a relation, the related table of a many_to_many declaring "ft".
+func relatedTable(kind string, options map[string]any) string {
+	relationTable, _ := options["table"].(string)
+	if ft, _ := options["ft"].(string); kind == "many_to_many" && ft != "" {
+		return ft
+	}
+
+	return relationTable
+}
+
+// hasRelatedColumn reports whether column is one of the declared columns of a relation,
+// or a column of its table when the relation doesn't restrict them.
+func hasRelatedColumn(db *gorm.DB, kind string, options map[string]any, column string) bool {
+	if declared := Columns(options["columns"]); len(declared) > 0 {
+		return contains(declared, column)
+	}
+
+	return orderPattern.MatchString(column) && database.HasColumn(db, relatedTable(kind, options), column)
+}
@@ -0,0 +1,254 @@
+package queries
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AttachHasMany loads every has_many relation declared in a single transformed record.
+func AttachHasMany(db *gorm.DB, transformer map[string]any) error {
+	return MultiAttachHasMany(db, []map[string]any{transformer})
+}
+
+// AttachManyToMany loads every many_to_many relation declared in a single transformed record.
+func AttachManyToMany(db *gorm.DB, transformer map[string]any) error {
+	return MultiAttachManyToMany(db, []map[string]any{transformer})
+}
+
+// MultiAttachHasMany loads the has_many relations of all records with one query per relation.
+func MultiAttachHasMany(db *gorm.DB, values []map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	relations, _ := values[0]["has_many"].(map[string]any)
+	ids := CollectValues(values, "id")
+
+	for name, relation := range relations {
+		options, _ := relation.(map[string]any)
+		table, _ := options["table"].(string)
+		fk, _ := options["fk"].(string)
+
+		grouped := map[string][]map[string]any{}
+
+		if table != "" && fk != "" && len(ids) > 0 {
+			items := []map[string]any{}
+			query := WithoutTrashed(db.Table(table).Where(table+"."+fk+" IN ?", ids), table)
+
+			if columns := Columns(options["columns"]); len(columns) > 0 {
+				if hasNested(options) {
+					columns = AppendUnique(columns, "id")
+				}
+				query = query.Select(QualifyColumns(table, AppendUnique(columns, fk)))
+			}
+
+			if err := query.Find(&items).Error; err != nil {
+				return fmt.Errorf("error while loading %v: %w", name, err)
+			}
+
+			if err := attachNested(db, items, options); err != nil {
+				return err
+			}
+			grouped = GroupBy(items, fk)
+		}
+
+		for _, value := range values {
+			value[name] = childrenOf(grouped, value["id"])
+		}
+	}
+
+	for _, value := range values {
+		delete(value, "has_many")
+	}
+
+	return nil
+}
+
+// MultiAttachManyToMany loads the many_to_many relations of all records with one query per relation.
+// The relation reads the pivot table, or joins the related table when "ft" is declared.
+func MultiAttachManyToMany(db *gorm.DB, values []map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	relations, _ := values[0]["many_to_many"].(map[string]any)
+	ids := CollectValues(values, "id")
+
+	for name, relation := range relations {
+		options, _ := relation.(map[string]any)
+		table, _ := options["table"].(string)
+		fk1, fk2 := PivotKeys(options)
+		ft, _ := options["ft"].(string)
+
+		grouped := map[string][]map[string]any{}
+
+		if table != "" && fk1 != "" && len(ids) > 0 {
+			items := []map[string]any{}
+			query := db.Table(table).Where(table+"."+fk1+" IN ?", ids)
+			columns := Columns(options["columns"])
+
+			if ft != "" && fk2 != "" {
+				query = WithoutTrashed(query.Joins("JOIN "+ft+" ON "+ft+".id = "+table+"."+fk2), ft)
+
+				if len(columns) == 0 {
+					columns = []string{ft + ".*"}
+				} else {
+					if hasNested(options) {
+						columns = AppendUnique(columns, "id")
+					}
+					columns = QualifyColumns(ft, columns)
+				}
+
+				query = query.Select(append(columns, table+"."+fk1+" AS "+fk1))
+			} else if len(columns) > 0 {
+				query = query.Select(QualifyColumns(table, AppendUnique(columns, fk1)))
+			}
+
+			if err := query.Find(&items).Error; err != nil {
+				return fmt.Errorf("error while loading %v: %w", name, err)
+			}
+
+			if err := attachNested(db, items, options); err != nil {
+				return err
+			}
+			grouped = GroupBy(items, fk1)
+		}
+
+		for _, value := range values {
+			value[name] = childrenOf(grouped, value["id"])
+		}
+	}
+
+	for _, value := range values {
+		delete(value, "many_to_many")
+	}
+
+	return nil
+}
+
+// attachNested loads the relations declared inside a relation on its rows, eg: the attributes of items.
+func attachNested(db *gorm.DB, items []map[string]any, options map[string]any) error {
+	if len(items) == 0 || !hasNested(options) {
+		return nil
+	}
+
+	for _, kind := range []string{"has_many", "many_to_many"} {
+		if nested, ok := options[kind].(map[string]any); ok {
+			for _, item := range items {
+				item[kind] = nested
+			}
+		}
+	}
+
+	if err := MultiAttachHasMany(db, items); err != nil {
+		return err
+	}
+
+	return MultiAttachManyToMany(db, items)
+}
+
+func hasNested(options map[string]any) bool {
+	hasMany, _ := options["has_many"].(map[string]any)
+	manyToMany, _ := options["many_to_many"].(map[string]any)
+
+	return len(hasMany) > 0 || len(manyToMany) > 0
+}
+
+// PivotKeys returns the pivot keys of a many_to_many relation, accepting both "fk_1" and legacy "fk1".
+func PivotKeys(options map[string]any) (string, string) {
+	fk1, _ := options["fk_1"].(string)
+	fk2, _ := options["fk_2"].(string)
+
+	if fk1 == "" {
+		fk1, _ = options["fk1"].(string)
+	}
+
+	if fk2 == "" {
+		fk2, _ = options["fk2"].(string)
+	}
+
+	return fk1, fk2
+}
+
+// Columns converts a transformer "columns" entry into a string slice.
+func Columns(value any) []string {
+	columns := []string{}
+
+	switch v := value.(type) {
+	case []string:
+		columns = append(columns, v...)
+	case []any:
+		for _, column := range v {
+			if s, ok := column.(string); ok && s != "" {
+				columns = append(columns, s)
+			}
+		}
+	}
+
+	return columns
+}
+
+// QualifyColumns prefixes every column with the table name.
+func QualifyColumns(table string, columns []string) []string {
+	qualified := make([]string, len(columns))
+
+	for i, column := range columns {
+		qualified[i] = table + "." + column
+	}
+
+	return qualified
+}
+
+// AppendUnique appends the value when it is not already part of the slice.
+func AppendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
+
+// CollectValues returns the non nil values of a key across records.
+func CollectValues(values []map[string]any, key string) []any {
+	collected := []any{}
+
+	for _, value := range values {
+		if value[key] != nil {
+			collected = append(collected, value[key])
+		}
+	}
+
+	return collected
+}
+
+// GroupBy indexes records by the string form of a key.
+func GroupBy(values []map[string]any, key string) map[string][]map[string]any {
+	grouped := map[string][]map[string]any{}
+
+	for _, value := range values {
+		k := Key(value[key])
+		grouped[k] = append(grouped[k], value)
+	}
+
+	return grouped
+}
+
+// Key normalises an id coming from different drivers (int32, int64, []byte...) into a map key.
+func Key(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+
+	return fmt.Sprint(value)
+}
+
+func childrenOf(grouped map[string][]map[string]any, id any) []map[string]any {
+	if children, ok := grouped[Key(id)]; ok {
+		return children
+	}
+
+	return []map[string]any{}
+}
@@ -0,0 +1,50 @@
+package queries
+
+import (
+	"strconv"
+
+	"github.com/62teknologi/62whale/app/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SetTrashedByQuery hides soft deleted rows unless "with_trashed" or "only_trashed" is requested.
+func SetTrashedByQuery(query *gorm.DB, table string, ctx *gin.Context) {
+	if !database.SoftDeletes(query, table) {
+		return
+	}
+
+	switch {
+	case Flag(ctx, "only_trashed"):
+		query.Where(table + "." + database.DeletedAt + " IS NOT NULL")
+	case Flag(ctx, "with_trashed"):
+	default:
+		query.Where(table + "." + database.DeletedAt + " IS NULL")
+	}
+}
+
+// WithoutTrashed hides soft deleted rows of table when it supports soft delete.
+func WithoutTrashed(query *gorm.DB, table string) *gorm.DB {
+	if database.SoftDeletes(query, table) {
+		return query.Where(table + "." + database.DeletedAt + " IS NULL")
+	}
+
+	return query
+}
+
+// Flag reports whether a boolean query parameter is set, eg: "?with_trashed" or "?with_trashed=true".
+func Flag(ctx *gin.Context, name string) bool {
+	value, ok := ctx.GetQuery(name)
+	if !ok {
+		return false
+	}
+
+	if value == "" {
+		return true
+	}
+
+	enabled, _ := strconv.ParseBool(value)
+
+	return enabled
+}
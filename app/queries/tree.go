@@ -0,0 +1,181 @@
+package queries
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/62teknologi/62whale/app/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// cycle columns added by the recursive query, never part of the response
+const (
+	treeDepth = "tree_depth"
+	treePath  = "tree_path"
+)
+
+// Depth reads the "depth" query parameter limiting how many levels of children are loaded, 0 when absent.
+func Depth(ctx *gin.Context) (int, error) {
+	value := ctx.Query("depth")
+	if value == "" {
+		return 0, nil
+	}
+
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 1 {
+		return 0, errors.New("depth must be a positive number")
+	}
+
+	return depth, nil
+}
+
+// Descendants loads the rows below the given parents of a self referencing table (parent_id) in a single query.
+// It uses a recursive CTE where supported, otherwise, eg: on mysql 5.7, it loads one level per query until depth
+// is reached or a level is empty. A depth of 0 means unlimited.
+func Descendants(db *gorm.DB, table string, ids []any, depth int) ([]map[string]any, error) {
+	rows := []map[string]any{}
+
+	if len(ids) == 0 {
+		return rows, nil
+	}
+
+	if pathType, ok := treePathTypes[db.Dialector.Name()]; ok && database.RecursiveCTE(db) {
+		if err := db.Raw(descendantsQuery(db, table, pathType, depth), ids).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		return uniqueRows(rows), nil
+	}
+
+	// a row is loaded once so cyclic data can't loop, Nest skips the cycles
+	seen := map[string]bool{}
+	parents := ids
+
+	for level := 1; len(parents) > 0 && (depth == 0 || level <= depth); level++ {
+		children := []map[string]any{}
+		query := db.Table(table).Where(table+".parent_id IN ?", parents).Where(table + ".id <> " + table + ".parent_id")
+
+		if err := WithoutTrashed(query, table).Find(&children).Error; err != nil {
+			return nil, err
+		}
+
+		parents = []any{}
+		for _, child := range children {
+			if seen[Key(child["id"])] {
+				continue
+			}
+
+			seen[Key(child["id"])] = true
+			rows = append(rows, child)
+			parents = append(parents, child["id"])
+		}
+	}
+
+	return rows, nil
+}
+
+// treePathTypes is the column type holding the visited ids of a branch, per driver supporting recursive CTEs.
+var treePathTypes = map[string]string{
+	"mysql":    "CHAR(4096)",
+	"postgres": "TEXT",
+	"sqlite":   "TEXT",
+}
+
+// descendantsQuery walks the tree down from the parents, a branch stops when it meets an id already in its path.
+func descendantsQuery(db *gorm.DB, table string, pathType string, depth int) string {
+	driver := db.Dialector.Name()
+	anchor := []string{"t.parent_id IN ?", "t.id <> t.parent_id"}
+	recursive := []string{"tree." + treePath + " NOT LIKE " + concat(driver, "'%,'", "t.id", "',%'")}
+
+	if database.SoftDeletes(db, table) {
+		anchor = append(anchor, "t."+database.DeletedAt+" IS NULL")
+		recursive = append(recursive, "t."+database.DeletedAt+" IS NULL")
+	}
+
+	if depth > 0 {
+		recursive = append(recursive, "tree."+treeDepth+" < "+strconv.Itoa(depth))
+	}
+
+	return "WITH RECURSIVE tree AS (" +
+		"SELECT t.*, 1 AS " + treeDepth + ", CAST(" + concat(driver, "','", "t.parent_id", "','", "t.id", "','") + " AS " + pathType + ") AS " + treePath +
+		" FROM " + table + " t WHERE " + strings.Join(anchor, " AND ") +
+		" UNION ALL " +
+		"SELECT t.*, tree." + treeDepth + " + 1, " + concat(driver, "tree."+treePath, "t.id", "','") +
+		" FROM " + table + " t JOIN tree ON t.parent_id = tree.id WHERE " + strings.Join(recursive, " AND ") +
+		") SELECT * FROM tree"
+}
+
+func concat(driver string, parts ...string) string {
+	if driver == "mysql" {
+		return "CONCAT(" + strings.Join(parts, ", ") + ")"
+	}
+
+	return strings.Join(parts, " || ")
+}
+
+// uniqueRows drops the rows reached through several parents and the cycle columns.
+func uniqueRows(rows []map[string]any) []map[string]any {
+	seen := map[string]bool{}
+	unique := []map[string]any{}
+
+	for _, row := range rows {
+		key := Key(row["id"])
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		delete(row, treeDepth)
+		delete(row, treePath)
+		unique = append(unique, row)
+	}
+
+	return unique
+}
+
+// Nest attaches under key the children of every root, recursively. nodes[i] is the response built from rows[i].
+// A row already part of its own ancestry is skipped so cyclic data can't loop, a depth of 0 means unlimited.
+func Nest(roots []map[string]any, rows []map[string]any, nodes []map[string]any, key string, depth int) {
+	children := map[string][]int{}
+	for i, row := range rows {
+		parent := Key(row["parent_id"])
+		children[parent] = append(children[parent], i)
+	}
+
+	var walk func(id any, ancestors map[string]bool, level int) []map[string]any
+	walk = func(id any, ancestors map[string]bool, level int) []map[string]any {
+		result := []map[string]any{}
+
+		if depth > 0 && level > depth {
+			return result
+		}
+
+		ancestors[Key(id)] = true
+		defer delete(ancestors, Key(id))
+
+		for _, i := range children[Key(id)] {
+			if ancestors[Key(rows[i]["id"])] {
+				continue
+			}
+
+			node := make(map[string]any, len(nodes[i])+1)
+			for k, v := range nodes[i] {
+				node[k] = v
+			}
+
+			node[key] = walk(rows[i]["id"], ancestors, level+1)
+			result = append(result, node)
+		}
+
+		return result
+	}
+
+	for _, root := range roots {
+		if root["id"] != nil {
+			root[key] = walk(root["id"], map[string]bool{}, 1)
+		}
+	}
+}
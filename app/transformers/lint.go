@@ -0,0 +1,315 @@
+package transformers
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ReservedKeys are transformer keys describing behaviour rather than a column.
+var ReservedKeys = map[string]bool{
+	"has_many":     true,
+	"many_to_many": true,
+	"belongs_to":   true,
+	"duplicate":    true,
+	"filterable":   true,
+	"searchable":   true,
+	"summary":      true,
+	"owner":        true,
+}
+
+// Lint validates every transformer below dir against its schema.
+// When db is not nil the referenced tables and columns are checked as well.
+func Lint(dir string, db *gorm.DB) []Problem {
+	problems := []Problem{}
+	schema := &schemaInspector{db: db, tables: map[string]map[string]bool{}}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		problems = append(problems, lintFile(dir, path, schema)...)
+
+		return nil
+	})
+
+	if err != nil {
+		problems = append(problems, Problem{File: dir, Message: err.Error()})
+	}
+
+	return problems
+}
+
+func lintFile(dir string, path string, schema *schemaInspector) []Problem {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+
+	transformer := map[string]any{}
+	if err := json.Unmarshal(content, &transformer); err != nil {
+		return []Problem{{File: path, Message: "invalid json: " + err.Error()}}
+	}
+
+	parts := strings.Split(nameOf(dir, path), "/")
+	if len(parts) != 3 {
+		return []Problem{{File: path, Message: "transformer must be located at <request|response>/<table>/<operation>.json or request/<table>/policy.json"}}
+	}
+
+	kind, table := parts[0], parts[1]
+	if kind == "request" && parts[2] == PolicyFile {
+		return lintPolicy(path, table, transformer, schema)
+	}
+
+	problems := []Problem{}
+
+	switch kind {
+	case "request":
+		problems = append(problems, RequestSchema.Validate(path, "", transformer)...)
+		if schema.db != nil && len(problems) == 0 {
+			problems = append(problems, schema.checkRequest(path, "", table, transformer)...)
+		}
+	case "response":
+		problems = append(problems, ResponseSchema.Validate(path, "", transformer)...)
+		if schema.db != nil && len(problems) == 0 {
+			problems = append(problems, schema.checkResponse(path, table, transformer)...)
+		}
+	default:
+		problems = append(problems, Problem{File: path, Message: "unknown transformer kind " + kind})
+	}
+
+	return problems
+}
+
+type schemaInspector struct {
+	db     *gorm.DB
+	tables map[string]map[string]bool
+}
+
+// columns returns the columns of a table, or false when the table doesn't exist.
+func (s *schemaInspector) columns(table string) (map[string]bool, bool) {
+	if columns, ok := s.tables[table]; ok {
+		return columns, columns != nil
+	}
+
+	var columns map[string]bool
+
+	if s.db.Migrator().HasTable(table) {
+		columns = map[string]bool{}
+		if types, err := s.db.Migrator().ColumnTypes(table); err == nil {
+			for _, column := range types {
+				columns[column.Name()] = true
+			}
+		}
+	}
+
+	s.tables[table] = columns
+
+	return columns, columns != nil
+}
+
+func (s *schemaInspector) hasTable(file string, pointer string, table string) []Problem {
+	if _, ok := s.columns(table); !ok {
+		return []Problem{{File: file, Pointer: pointer, Message: "table " + table + " doesn't exist"}}
+	}
+
+	return nil
+}
+
+func (s *schemaInspector) hasColumn(file string, pointer string, table string, column string) []Problem {
+	columns, ok := s.columns(table)
+	if ok && !columns[column] {
+		return []Problem{{File: file, Pointer: pointer, Message: "column " + table + "." + column + " doesn't exist"}}
+	}
+
+	return nil
+}
+
+func (s *schemaInspector) hasColumns(file string, pointer string, table string, value any) []Problem {
+	problems := []Problem{}
+
+	list, _ := value.([]any)
+	for i, column := range list {
+		if name, ok := column.(string); ok {
+			problems = append(problems, s.hasColumn(file, pointer+"/"+strconv.Itoa(i), table, name)...)
+		}
+	}
+
+	return problems
+}
+
+func (s *schemaInspector) checkRequest(file string, pointer string, table string, transformer map[string]any) []Problem {
+	problems := s.hasTable(file, pointer, table)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	hasMany, _ := transformer["has_many"].(map[string]any)
+	manyToMany, _ := transformer["many_to_many"].(map[string]any)
+
+	if owner, ok := transformer["owner"].(string); ok {
+		problems = append(problems, s.hasColumn(file, pointer+"/owner", table, owner)...)
+	}
+
+	filterable, _ := transformer["filterable"].(map[string]any)
+	for _, key := range SortedKeys(filterable) {
+		problems = append(problems, s.hasFilterColumn(file, pointer+"/filterable/"+EscapePointer(key), table, transformer, key)...)
+	}
+
+	for _, name := range SortedKeys(hasMany) {
+		relation, _ := hasMany[name].(map[string]any)
+		relationPointer := pointer + "/has_many/" + EscapePointer(name)
+		problems = append(problems, s.checkRelation(file, relationPointer, relation, "fk")...)
+
+		relationTable, _ := relation["table"].(string)
+		if items, ok := transformer[name].([]any); ok && relationTable != "" {
+			for i, item := range items {
+				if fields, ok := item.(map[string]any); ok {
+					nested := map[string]any{}
+					for key, value := range fields {
+						nested[key] = value
+					}
+					nested["has_many"] = relation["has_many"]
+					problems = append(problems, s.checkRequest(file, pointer+"/"+EscapePointer(name)+"/"+strconv.Itoa(i), relationTable, nested)...)
+				}
+			}
+		}
+	}
+
+	for _, name := range SortedKeys(manyToMany) {
+		relation, _ := manyToMany[name].(map[string]any)
+		problems = append(problems, s.checkRelation(file, pointer+"/many_to_many/"+EscapePointer(name), relation, "fk_1", "fk_2")...)
+	}
+
+	for _, key := range SortedKeys(transformer) {
+		if ReservedKeys[key] || hasMany[key] != nil || manyToMany[key] != nil {
+			continue
+		}
+
+		problems = append(problems, s.hasColumn(file, pointer+"/"+EscapePointer(key), table, key)...)
+	}
+
+	return problems
+}
+
+func (s *schemaInspector) checkResponse(file string, table string, transformer map[string]any) []Problem {
+	problems := s.hasTable(file, "", table)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	for _, key := range SortedKeys(transformer) {
+		if !ReservedKeys[key] {
+			problems = append(problems, s.hasColumn(file, "/"+EscapePointer(key), table, key)...)
+		}
+	}
+
+	filterable, _ := transformer["filterable"].(map[string]any)
+	for _, key := range SortedKeys(filterable) {
+		problems = append(problems, s.hasFilterColumn(file, "/filterable/"+EscapePointer(key), table, transformer, key)...)
+	}
+
+	problems = append(problems, s.hasColumns(file, "/searchable", table, transformer["searchable"])...)
+
+	belongsTo, _ := transformer["belongs_to"].(map[string]any)
+	for _, name := range SortedKeys(belongsTo) {
+		relation, _ := belongsTo[name].(map[string]any)
+		relationPointer := "/belongs_to/" + EscapePointer(name)
+		relationTable, _ := relation["table"].(string)
+		fk, _ := relation["fk"].(string)
+
+		problems = append(problems, s.hasColumn(file, relationPointer+"/fk", table, fk)...)
+		problems = append(problems, s.hasTable(file, relationPointer+"/table", relationTable)...)
+		problems = append(problems, s.hasColumns(file, relationPointer+"/columns", relationTable, relation["columns"])...)
+	}
+
+	return append(problems, s.checkResponseRelations(file, "", transformer)...)
+}
+
+// checkResponseRelations verifies the has_many and many_to_many relations declared at pointer and their nested ones.
+func (s *schemaInspector) checkResponseRelations(file string, pointer string, declaration map[string]any) []Problem {
+	problems := []Problem{}
+
+	hasMany, _ := declaration["has_many"].(map[string]any)
+	for _, name := range SortedKeys(hasMany) {
+		relation, _ := hasMany[name].(map[string]any)
+		relationPointer := pointer + "/has_many/" + EscapePointer(name)
+		problems = append(problems, s.checkRelation(file, relationPointer, relation, "fk")...)
+		problems = append(problems, s.checkResponseRelations(file, relationPointer, relation)...)
+	}
+
+	manyToMany, _ := declaration["many_to_many"].(map[string]any)
+	for _, name := range SortedKeys(manyToMany) {
+		relation, _ := manyToMany[name].(map[string]any)
+		relationPointer := pointer + "/many_to_many/" + EscapePointer(name)
+		problems = append(problems, s.checkRelation(file, relationPointer, relation, "fk_1", "fk_2")...)
+		problems = append(problems, s.checkResponseRelations(file, relationPointer, relation)...)
+	}
+
+	return problems
+}
+
+// hasFilterColumn checks a filterable key, a dotted key names a column of a relation declared by the transformer,
+// eg: "category.slug".
+func (s *schemaInspector) hasFilterColumn(file string, pointer string, table string, transformer map[string]any, key string) []Problem {
+	name, column, related := strings.Cut(key, ".")
+	if !related {
+		return s.hasColumn(file, pointer, table, key)
+	}
+
+	for _, kind := range []string{"belongs_to", "has_many", "many_to_many"} {
+		relations, _ := transformer[kind].(map[string]any)
+		if relation, ok := relations[name].(map[string]any); ok {
+			relationTable, _ := relation["table"].(string)
+			if ft, _ := relation["ft"].(string); kind == "many_to_many" && ft != "" {
+				relationTable = ft
+			}
+
+			return s.hasColumn(file, pointer, relationTable, column)
+		}
+	}
+
+	return []Problem{{File: file, Pointer: pointer, Message: "relation " + name + " isn't declared"}}
+}
+
+// checkRelation verifies the relation table, its foreign keys and selected columns.
+func (s *schemaInspector) checkRelation(file string, pointer string, relation map[string]any, keys ...string) []Problem {
+	table, _ := relation["table"].(string)
+	problems := s.hasTable(file, pointer+"/table", table)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	for _, key := range keys {
+		column, ok := relation[key].(string)
+		for alias, target := range manyToManySchema.Aliases {
+			if !ok && target == key {
+				column, ok = relation[alias].(string)
+				key = alias
+			}
+		}
+		problems = append(problems, s.hasColumn(file, pointer+"/"+key, table, column)...)
+	}
+
+	columnsTable := table
+	if ft, ok := relation["ft"].(string); ok && ft != "" {
+		problems = append(problems, s.hasTable(file, pointer+"/ft", ft)...)
+		if len(keys) > 1 {
+			columnsTable = ft
+		}
+	}
+
+	problems = append(problems, s.hasColumns(file, pointer+"/columns", columnsTable, relation["columns"])...)
+
+	return problems
+}
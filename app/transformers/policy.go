@@ -0,0 +1,138 @@
+package transformers
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Operations lists what a catalog route can do, as named by catalogs and policies.
+var Operations = []string{
+	"find",
+	"find_all",
+	"create",
+	"bulk_create",
+	"update",
+	"patch",
+	"delete",
+	"delete_by_query",
+	"restore",
+	"force_delete",
+}
+
+// ReadOperations are the operations covered by the "read" policy entry, every other one is a "write".
+var ReadOperations = map[string]bool{"find": true, "find_all": true}
+
+// PolicyFile names the policy of a catalog, it sits next to its request transformers: request/<table>/policy.json.
+const PolicyFile = "policy"
+
+var rolesSchema = &Schema{Type: "array", Items: &Schema{Type: "string"}}
+
+// PolicySchema validates request/<table>/policy.json files.
+var PolicySchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"operations": {Type: "object", Additional: rolesSchema},
+		"fields":     {Type: "object", Additional: rolesSchema},
+	},
+}
+
+// PolicyName returns the registry name of the policy of table.
+func PolicyName(table string) string {
+	return "request/" + table + "/" + PolicyFile
+}
+
+// IsOperation reports whether name is a catalog operation.
+func IsOperation(name string) bool {
+	for _, operation := range Operations {
+		if operation == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lintPolicy checks a policy file, its operations must be known and its fields must be columns of the table.
+func lintPolicy(path string, table string, policy map[string]any, schema *schemaInspector) []Problem {
+	problems := PolicySchema.Validate(path, "", policy)
+	if len(problems) > 0 {
+		return problems
+	}
+
+	operations, _ := policy["operations"].(map[string]any)
+	for _, key := range SortedKeys(operations) {
+		if key != "read" && key != "write" && !IsOperation(key) {
+			problems = append(problems, Problem{File: path, Pointer: "/operations/" + EscapePointer(key), Message: "unknown operation " + key})
+		}
+	}
+
+	fields, _ := policy["fields"].(map[string]any)
+	for _, key := range SortedKeys(fields) {
+		pointer := "/fields/" + EscapePointer(key)
+
+		owner, column, err := policyField(path, table, key)
+		if err != nil {
+			problems = append(problems, Problem{File: path, Pointer: pointer, Message: err.Error()})
+			continue
+		}
+
+		if schema.db != nil && column != "" {
+			problems = append(problems, schema.hasColumn(path, pointer, owner, column)...)
+		}
+	}
+
+	return problems
+}
+
+// policyField resolves the table and column written by a policy field, eg: "items.price" is the price column of the
+// "items" relation declared by the request transformers next to the policy. A field naming a relation has no column.
+func policyField(path string, table string, field string) (string, string, error) {
+	parts := strings.Split(field, ".")
+
+	for _, operation := range []string{"create", "update"} {
+		transformer, err := parseFile(filepath.Join(filepath.Dir(path), operation+".json"))
+		if err != nil {
+			continue
+		}
+
+		if owner, column, ok := followRelations(table, transformer, parts); ok {
+			return owner, column, nil
+		}
+	}
+
+	if len(parts) == 1 {
+		return table, field, nil
+	}
+
+	return "", "", errors.New("unknown relation " + strings.Join(parts[:len(parts)-1], "."))
+}
+
+// followRelations walks the has_many relations of a request transformer down to the last part of a field.
+func followRelations(table string, transformer map[string]any, parts []string) (string, string, bool) {
+	hasMany, _ := transformer["has_many"].(map[string]any)
+	manyToMany, _ := transformer["many_to_many"].(map[string]any)
+
+	for i, name := range parts {
+		last := i == len(parts)-1
+
+		if _, ok := manyToMany[name]; ok && last {
+			return table, "", true
+		}
+
+		options, ok := hasMany[name].(map[string]any)
+		if !ok {
+			return table, name, last
+		}
+
+		if last {
+			return table, "", true
+		}
+
+		table, _ = options["table"].(string)
+		hasMany, _ = options["has_many"].(map[string]any)
+		manyToMany = nil
+	}
+
+	return table, "", false
+}
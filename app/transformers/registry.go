@@ -0,0 +1,292 @@
+package transformers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/metrics"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	mu      sync.RWMutex
+	root    string
+	files   = map[string]map[string]any{}
+	watcher *fsnotify.Watcher
+)
+
+// Load parses every transformer below dir and replaces the registry content.
+// It reports all files that can't be parsed so misconfiguration shows up at boot.
+func Load(dir string) error {
+	parsed, err := parseTree(dir)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	root = dir
+	files = parsed
+	mu.Unlock()
+
+	// the new transformers may rely on columns added since they were looked up
+	database.ResetColumns()
+
+	return nil
+}
+
+// Reload parses the whole tree again, the current content is kept when any file is invalid.
+func Reload() error {
+	mu.RLock()
+	dir := root
+	mu.RUnlock()
+
+	return Load(dir)
+}
+
+// Get returns a copy of the transformer registered under name, eg: "response/products/find".
+// The copy can be mutated freely by the caller, catalog settings such as "owner" are left out.
+func Get(name string) (map[string]any, error) {
+	mu.RLock()
+	transformer, ok := files[name]
+	mu.RUnlock()
+
+	if !ok {
+		metrics.TransformerLookups.WithLabelValues("miss").Inc()
+		return nil, fmt.Errorf("transformer %s not found", name)
+	}
+
+	metrics.TransformerLookups.WithLabelValues("hit").Inc()
+
+	copied := Copy(transformer)
+	delete(copied, "owner")
+
+	return copied, nil
+}
+
+// Owner returns the column holding the owner of the rows of table, declared by "owner" in its request transformers.
+func Owner(table string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, operation := range []string{"create", "update", "delete"} {
+		if owner, ok := files["request/"+table+"/"+operation]["owner"].(string); ok && owner != "" {
+			return owner
+		}
+	}
+
+	return ""
+}
+
+// Names returns every registered transformer name in a stable order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Watch reloads a transformer as soon as its file changes on disk.
+func Watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	mu.RLock()
+	dir := root
+	mu.RUnlock()
+
+	if err := addDirs(w, dir); err != nil {
+		w.Close()
+		return err
+	}
+
+	mu.Lock()
+	watcher = w
+	mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				handleEvent(w, dir, event)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("transformer watcher failed", "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close stops watching the transformer tree.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if watcher == nil {
+		return nil
+	}
+
+	err := watcher.Close()
+	watcher = nil
+
+	return err
+}
+
+// Copy deep copies a parsed transformer.
+func Copy(transformer map[string]any) map[string]any {
+	return copyValue(transformer).(map[string]any)
+}
+
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+
+	return value
+}
+
+func handleEvent(w *fsnotify.Watcher, dir string, event fsnotify.Event) {
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if err := addDirs(w, event.Name); err != nil {
+				slog.Error("cannot watch transformer directory", "path", event.Name, "error", err)
+			}
+			return
+		}
+	}
+
+	if filepath.Ext(event.Name) != ".json" {
+		return
+	}
+
+	name := nameOf(dir, event.Name)
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		mu.Lock()
+		delete(files, name)
+		mu.Unlock()
+		return
+	}
+
+	if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+		transformer, err := parseFile(event.Name)
+		if err != nil {
+			slog.Warn("keeping previous transformer", "transformer", name, "error", err)
+			return
+		}
+
+		mu.Lock()
+		files[name] = transformer
+		mu.Unlock()
+		database.ResetColumns()
+
+		slog.Info("transformer reloaded", "transformer", name)
+	}
+}
+
+func addDirs(w *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return w.Add(path)
+		}
+
+		return nil
+	})
+}
+
+func parseTree(dir string) (map[string]map[string]any, error) {
+	parsed := map[string]map[string]any{}
+	problems := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		transformer, err := parseFile(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			return nil
+		}
+
+		parsed[nameOf(dir, path)] = transformer
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid transformers:\n%s", strings.Join(problems, "\n"))
+	}
+
+	return parsed, nil
+}
+
+func parseFile(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	transformer := map[string]any{}
+
+	if err := json.Unmarshal(content, &transformer); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return transformer, nil
+}
+
+func nameOf(dir string, path string) string {
+	name, err := filepath.Rel(dir, path)
+	if err != nil {
+		name = path
+	}
+
+	return strings.TrimSuffix(filepath.ToSlash(name), ".json")
+}
@@ -0,0 +1,345 @@
+package transformers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema describes the allowed shape of a transformer node.
+type Schema struct {
+	// Type is one of "object", "array", "string", "rule" or "any".
+	Type string
+	// Properties lists the known keys of an object.
+	Properties map[string]*Schema
+	// Required lists the keys an object must declare.
+	Required []string
+	// Additional validates object keys not listed in Properties, nil rejects them.
+	Additional *Schema
+	// Items validates every element of an array.
+	Items *Schema
+	// Enum restricts a string to a fixed set of values.
+	Enum []string
+	// Aliases maps legacy keys to the key they stand for, they are reported and validated as that key.
+	Aliases map[string]string
+	// OneOf accepts the first schema whose type matches the value.
+	OneOf []*Schema
+}
+
+// Problem is a single violation found in a transformer file.
+type Problem struct {
+	File    string
+	Pointer string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.File + "#" + p.Pointer + ": " + p.Message
+}
+
+// Rules lists the validation rules understood in request transformers.
+var Rules = map[string]bool{
+	"required": false,
+	"number":   false,
+	"string":   false,
+	"email":    false,
+	"min":      true,
+	"max":      true,
+}
+
+// FilterTypes lists the types accepted by "filterable" entries.
+var FilterTypes = []string{"int", "string", "timestamp"}
+
+var columnsSchema = &Schema{Type: "array", Items: &Schema{Type: "string"}}
+
+var hasManySchema = &Schema{
+	Type:     "object",
+	Required: []string{"table", "fk"},
+	Properties: map[string]*Schema{
+		"table":   {Type: "string"},
+		"fk":      {Type: "string"},
+		"ft":      {Type: "string"},
+		"columns": columnsSchema,
+	},
+}
+
+var manyToManySchema = &Schema{
+	Type:     "object",
+	Required: []string{"table", "fk_1", "fk_2"},
+	Properties: map[string]*Schema{
+		"table":   {Type: "string"},
+		"fk_1":    {Type: "string"},
+		"fk_2":    {Type: "string"},
+		"ft":      {Type: "string"},
+		"columns": columnsSchema,
+	},
+	Aliases: map[string]string{"fk1": "fk_1", "fk2": "fk_2"},
+}
+
+var belongsToSchema = &Schema{
+	Type:     "object",
+	Required: []string{"table", "fk"},
+	Properties: map[string]*Schema{
+		"table":   {Type: "string"},
+		"fk":      {Type: "string"},
+		"columns": columnsSchema,
+	},
+}
+
+// RequestSchema validates request/<table>/<operation>.json files.
+var RequestSchema = &Schema{Type: "object"}
+
+// ResponseSchema validates response/<table>/<operation>.json files.
+var ResponseSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"filterable": {Type: "object", Additional: &Schema{Type: "string", Enum: FilterTypes}},
+		"searchable": columnsSchema,
+		"summary":    {Type: "any"},
+		"belongs_to": {Type: "object", Additional: belongsToSchema},
+	},
+	Additional: &Schema{Type: "string"},
+}
+
+func init() {
+	field := &Schema{}
+	field.OneOf = []*Schema{
+		{Type: "rule"},
+		{Type: "array", Items: &Schema{OneOf: []*Schema{{Type: "rule"}, RequestSchema}}},
+	}
+
+	nestedHasMany := &Schema{}
+	*nestedHasMany = *hasManySchema
+	nestedHasMany.Properties = map[string]*Schema{
+		"table":    {Type: "string"},
+		"fk":       {Type: "string"},
+		"ft":       {Type: "string"},
+		"columns":  columnsSchema,
+		"has_many": {Type: "object", Additional: nestedHasMany},
+	}
+	nestedHasMany.Additional = field
+
+	RequestSchema.Properties = map[string]*Schema{
+		"owner":        {Type: "string"},
+		"filterable":   ResponseSchema.Properties["filterable"],
+		"has_many":     {Type: "object", Additional: nestedHasMany},
+		"many_to_many": {Type: "object", Additional: manyToManySchema},
+		"duplicate": {Type: "object", Additional: &Schema{
+			Type:       "object",
+			Required:   []string{"columns"},
+			Properties: map[string]*Schema{"columns": columnsSchema},
+		}},
+	}
+	RequestSchema.Additional = field
+
+	// response relations may declare the relations of their own rows, eg: the attributes of items
+	responseHasMany := &Schema{}
+	*responseHasMany = *hasManySchema
+	responseManyToMany := &Schema{}
+	*responseManyToMany = *manyToManySchema
+
+	for _, relation := range []*Schema{responseHasMany, responseManyToMany} {
+		properties := map[string]*Schema{
+			"has_many":     {Type: "object", Additional: responseHasMany},
+			"many_to_many": {Type: "object", Additional: responseManyToMany},
+		}
+		for key, property := range relation.Properties {
+			properties[key] = property
+		}
+		relation.Properties = properties
+	}
+
+	ResponseSchema.Properties["has_many"] = &Schema{Type: "object", Additional: responseHasMany}
+	ResponseSchema.Properties["many_to_many"] = &Schema{Type: "object", Additional: responseManyToMany}
+}
+
+// Validate checks value against the schema and returns every violation.
+func (s *Schema) Validate(file string, pointer string, value any) []Problem {
+	problem := func(format string, args ...any) []Problem {
+		return []Problem{{File: file, Pointer: pointer, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if len(s.OneOf) > 0 {
+		for _, candidate := range s.OneOf {
+			if candidate.matches(value) {
+				return candidate.Validate(file, pointer, value)
+			}
+		}
+		return problem("unexpected %s", typeOf(value))
+	}
+
+	switch s.Type {
+	case "any", "":
+		return nil
+	case "string":
+		v, ok := value.(string)
+		if !ok {
+			return problem("expected string, got %s", typeOf(value))
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, v) {
+			return problem("%q must be one of %s", v, strings.Join(s.Enum, ", "))
+		}
+		return nil
+	case "rule":
+		v, ok := value.(string)
+		if !ok {
+			return problem("expected validation rule, got %s", typeOf(value))
+		}
+		return validateRule(file, pointer, v)
+	case "array":
+		v, ok := value.([]any)
+		if !ok {
+			return problem("expected array, got %s", typeOf(value))
+		}
+		problems := []Problem{}
+		for i, item := range v {
+			problems = append(problems, s.Items.Validate(file, pointer+"/"+strconv.Itoa(i), item)...)
+		}
+		return problems
+	case "object":
+		v, ok := value.(map[string]any)
+		if !ok {
+			return problem("expected object, got %s", typeOf(value))
+		}
+		return s.validateObject(file, pointer, v)
+	}
+
+	return problem("unknown schema type %s", s.Type)
+}
+
+func (s *Schema) validateObject(file string, pointer string, value map[string]any) []Problem {
+	problems := []Problem{}
+
+	for _, key := range s.Required {
+		if _, ok := value[key]; !ok && !s.hasAlias(value, key) {
+			problems = append(problems, Problem{File: file, Pointer: pointer, Message: "required key " + key + " is missing"})
+		}
+	}
+
+	for _, key := range SortedKeys(value) {
+		child := pointer + "/" + EscapePointer(key)
+
+		if alias, ok := s.Aliases[key]; ok {
+			if _, ok := value[alias]; ok {
+				problems = append(problems, Problem{File: file, Pointer: child, Message: key + " is an alias of " + alias + ", use only one of them"})
+				continue
+			}
+
+			problems = append(problems, Problem{File: file, Pointer: child, Message: key + " is the legacy spelling of " + alias + ", rename it"})
+			problems = append(problems, s.Properties[alias].Validate(file, child, value[key])...)
+			continue
+		}
+
+		if property, ok := s.Properties[key]; ok {
+			problems = append(problems, property.Validate(file, child, value[key])...)
+			continue
+		}
+
+		if s.Additional == nil {
+			problems = append(problems, Problem{File: file, Pointer: child, Message: "unknown key " + key})
+			continue
+		}
+
+		problems = append(problems, s.Additional.Validate(file, child, value[key])...)
+	}
+
+	return problems
+}
+
+// hasAlias reports whether key is given through one of its aliases.
+func (s *Schema) hasAlias(value map[string]any, key string) bool {
+	for alias, target := range s.Aliases {
+		if _, ok := value[alias]; ok && target == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Schema) matches(value any) bool {
+	switch value.(type) {
+	case string:
+		return s.Type == "string" || s.Type == "rule" || s.Type == "any"
+	case []any:
+		return s.Type == "array" || s.Type == "any"
+	case map[string]any:
+		return s.Type == "object" || s.Type == "any"
+	}
+
+	return s.Type == "any"
+}
+
+func validateRule(file string, pointer string, rule string) []Problem {
+	problems := []Problem{}
+
+	if rule == "" {
+		return problems
+	}
+
+	for _, part := range strings.Split(rule, "|") {
+		name, arg, hasArg := strings.Cut(part, ":")
+		needsArg, known := Rules[name]
+
+		switch {
+		case !known:
+			problems = append(problems, Problem{File: file, Pointer: pointer, Message: "unknown rule " + name})
+		case needsArg && !hasArg:
+			problems = append(problems, Problem{File: file, Pointer: pointer, Message: "rule " + name + " needs an argument"})
+		case needsArg:
+			if _, err := strconv.Atoi(arg); err != nil {
+				problems = append(problems, Problem{File: file, Pointer: pointer, Message: "rule " + name + " argument must be a number"})
+			}
+		case hasArg:
+			problems = append(problems, Problem{File: file, Pointer: pointer, Message: "rule " + name + " doesn't take an argument"})
+		}
+	}
+
+	return problems
+}
+
+// SortedKeys returns the keys of a map in a stable order.
+func SortedKeys(value map[string]any) []string {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// EscapePointer escapes a key to be used as a JSON pointer segment.
+func EscapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,37 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, injected at build time, eg:
+// go build -ldflags "-X github.com/62teknologi/62whale/app/version.Version=v1.2.0 -X github.com/62teknologi/62whale/app/version.Commit=$(git rev-parse HEAD) -X github.com/62teknologi/62whale/app/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info returns the build metadata, commit and build time fall back on the vcs stamp of the go toolchain.
+func Info() map[string]any {
+	commit, buildTime := Commit, BuildTime
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && commit == "":
+				commit = setting.Value
+			case setting.Key == "vcs.time" && buildTime == "":
+				buildTime = setting.Value
+			}
+		}
+	}
+
+	return map[string]any{
+		"version":    Version,
+		"commit":     commit,
+		"build_time": buildTime,
+		"go_version": runtime.Version(),
+	}
+}
@@ -5,13 +5,16 @@ OUTPUT="binary"
 git submodule init
 git submodule update
 go mod tidy
+#build metadata served by /version
+VERSION_PKG="github.com/62teknologi/62whale/app/version"
+LDFLAGS="-X ${VERSION_PKG}.Version=$(git describe --tags --always) -X ${VERSION_PKG}.Commit=$(git rev-parse HEAD) -X ${VERSION_PKG}.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
 #building section
 ARCH="amd64"
-env GOOS=darwin GOARCH=amd64 go build -x -v -o ${APP_NAME}-mac-${ARCH} main.go
-env GOOS=linux GOARCH=amd64 go build -x -v -o ${APP_NAME}-linux-${ARCH} main.go
-env GOOS=windows GOARCH=amd64 go build -x -v -o ${APP_NAME}-windows-${ARCH}.exe main.go
+env GOOS=darwin GOARCH=amd64 go build -x -v -ldflags "${LDFLAGS}" -o ${APP_NAME}-mac-${ARCH} main.go
+env GOOS=linux GOARCH=amd64 go build -x -v -ldflags "${LDFLAGS}" -o ${APP_NAME}-linux-${ARCH} main.go
+env GOOS=windows GOARCH=amd64 go build -x -v -ldflags "${LDFLAGS}" -o ${APP_NAME}-windows-${ARCH}.exe main.go
 ARCH="arm64"
-env GOOS=darwin GOARCH=arm64 go build -x -v -o ${APP_NAME}-mac-${ARCH} main.go
+env GOOS=darwin GOARCH=arm64 go build -x -v -ldflags "${LDFLAGS}" -o ${APP_NAME}-mac-${ARCH} main.go
 #move binary to ./build dir
 rm -rf ${OUTPUT} || exit_on_error "${OUTPUT} folder didn't exist"
 mkdir ${OUTPUT} || exit_on_error "${OUTPUT} folder exist"
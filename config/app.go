@@ -1,46 +1,107 @@
 package config
 
 import (
-	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"golang.org/x/exp/slog"
 )
 
 type Config struct {
-	HTTPServerAddress string `mapstructure:"HTTP_SERVER_ADDRESS"`
-	DBDriver          string `mapstructure:"DB_DRIVER"`
-	DBSource1         string `mapstructure:"DB_SOURCE_1"`
-	DBSource2         string `mapstructure:"DB_SOURCE_2"`
-	SettingPath       string `mapstructure:"SETTING_PATH"`
+	HTTPServerAddress string            `mapstructure:"HTTP_SERVER_ADDRESS"`
+	DBDriver          string            `mapstructure:"DB_DRIVER"`
+	DBSource1         string            `mapstructure:"DB_SOURCE_1"`
+	DBSource2         string            `mapstructure:"DB_SOURCE_2"`
+	DBDefaultSource   string            `mapstructure:"DB_DEFAULT_SOURCE"`
+	DBSources         map[string]string `mapstructure:"-"`
+	SettingPath       string            `mapstructure:"SETTING_PATH"`
+	MigrationPath     string            `mapstructure:"MIGRATION_PATH"`
+	AuthJWTSecret     string            `mapstructure:"AUTH_JWT_SECRET"`
+	AuthJWKSFile      string            `mapstructure:"AUTH_JWKS_FILE"`
+	AuthJWTIssuer     string            `mapstructure:"AUTH_JWT_ISSUER"`
+	AuthJWTAudience   string            `mapstructure:"AUTH_JWT_AUDIENCE"`
+	AuthAPIKeyTable   string            `mapstructure:"AUTH_API_KEY_TABLE"`
+	AuthAdminRole     string            `mapstructure:"AUTH_ADMIN_ROLE"`
+	LogLevel          string            `mapstructure:"LOG_LEVEL"`
+	LogFormat         string            `mapstructure:"LOG_FORMAT"`
+	HTTPReadTimeout   time.Duration     `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout  time.Duration     `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout   time.Duration     `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout   time.Duration     `mapstructure:"SHUTDOWN_TIMEOUT"`
 }
 
 var Data Config
 
+// dbSourcePrefix marks a named data source, eg: DB_SOURCE_REPLICA becomes "replica".
+const dbSourcePrefix = "DB_SOURCE_"
+
 // LoadConfig reads configuration from file or environment variables.
 func LoadConfig(path string, data *Config) (config Config, err error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigFile(".env")
 
 	viper.SetDefault("HTTP_SERVER_ADDRESS", "0.0.0.0:10081")
+	viper.SetDefault("HTTP_READ_TIMEOUT", "15s")
+	viper.SetDefault("HTTP_WRITE_TIMEOUT", "30s")
+	viper.SetDefault("HTTP_IDLE_TIMEOUT", "60s")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
 	viper.SetDefault("DB_DRIVER", "mysql")
 	viper.SetDefault("DB_SOURCE_1", "root@tcp(127.0.0.1:3306)/whale_local?charset=utf8mb4&parseTime=True&loc=Local")
 	viper.SetDefault("DB_SOURCE_2", "")
+	viper.SetDefault("DB_DEFAULT_SOURCE", "1")
 
 	viper.SetDefault("SETTING_PATH", "setting")
+	viper.SetDefault("MIGRATION_PATH", "database/migrations")
+
+	viper.SetDefault("AUTH_JWT_SECRET", "")
+	viper.SetDefault("AUTH_JWKS_FILE", "")
+	viper.SetDefault("AUTH_JWT_ISSUER", "")
+	viper.SetDefault("AUTH_JWT_AUDIENCE", "")
+	viper.SetDefault("AUTH_API_KEY_TABLE", "")
+	viper.SetDefault("AUTH_ADMIN_ROLE", "admin")
+
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
 
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
 	if err != nil {
-		fmt.Println(err)
+		slog.Warn("cannot read config file", "error", err)
 	}
 
 	err = viper.Unmarshal(data)
 	if err != nil {
-		fmt.Println(err)
+		slog.Error("cannot decode config", "error", err)
 	}
 
-	fmt.Println("setting path : " + data.SettingPath)
+	data.DBSources = loadDBSources()
 
 	return *data, err
 }
+
+// loadDBSources collects every non empty DB_SOURCE_<NAME> from the config file and environment.
+func loadDBSources() map[string]string {
+	keys := viper.AllKeys()
+
+	for _, env := range os.Environ() {
+		keys = append(keys, strings.SplitN(env, "=", 2)[0])
+	}
+
+	sources := map[string]string{}
+
+	for _, key := range keys {
+		key = strings.ToUpper(key)
+		if !strings.HasPrefix(key, dbSourcePrefix) {
+			continue
+		}
+
+		if dsn := viper.GetString(key); dsn != "" {
+			sources[strings.ToLower(strings.TrimPrefix(key, dbSourcePrefix))] = dsn
+		}
+	}
+
+	return sources
+}
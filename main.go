@@ -1,34 +1,140 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/62teknologi/62whale/62golib/utils"
+	"github.com/62teknologi/62whale/app/auth"
+	"github.com/62teknologi/62whale/app/catalogs"
+	"github.com/62teknologi/62whale/app/commands"
+	"github.com/62teknologi/62whale/app/database"
 	"github.com/62teknologi/62whale/app/http/controllers"
 	"github.com/62teknologi/62whale/app/http/middlewares"
 	"github.com/62teknologi/62whale/app/interfaces"
+	"github.com/62teknologi/62whale/app/logger"
+	"github.com/62teknologi/62whale/app/metrics"
+	"github.com/62teknologi/62whale/app/transformers"
 	"github.com/62teknologi/62whale/config"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
 )
 
 func main() {
 
 	configs, err := config.LoadConfig(".", &config.Data)
 	if err != nil {
-		fmt.Printf("cannot load config: %w", err)
+		slog.Error("cannot load config", "error", err)
 		return
 	}
 
-	// todo : replace last variable with spread notation "..."
-	utils.ConnectDatabase(configs.DBDriver, configs.DBSource1, configs.DBSource2)
+	if err := logger.Setup(configs); err != nil {
+		slog.Error("cannot setup logger", "error", err)
+		return
+	}
+
+	slog.Debug("config loaded", "setting_path", configs.SettingPath)
+
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
+	if err := database.Connect(configs.DBDriver, configs.DBSources, configs.DBDefaultSource); err != nil {
+		slog.Error("cannot connect to database", "error", err)
+		return
+	}
+
+	defer func() {
+		if err := database.Close(); err != nil {
+			slog.Error("cannot close database", "error", err)
+		}
+	}()
+
+	if err := setup(configs); err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	if err := transformers.Watch(); err != nil {
+		slog.Warn("cannot watch transformers", "error", err)
+	}
+
+	defer transformers.Close()
+
+	r, err := newRouter(configs)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+
+	server := &http.Server{
+		Addr:              configs.HTTPServerAddress,
+		Handler:           r,
+		ReadTimeout:       configs.HTTPReadTimeout,
+		ReadHeaderTimeout: configs.HTTPReadTimeout,
+		WriteTimeout:      configs.HTTPWriteTimeout,
+		IdleTimeout:       configs.HTTPIdleTimeout,
+	}
+
+	if err := serve(server, configs.ShutdownTimeout); err != nil {
+		slog.Error("cannot run server", "error", err)
+		return
+	}
+
+	slog.Info("server stopped")
+}
+
+// setup loads everything the router needs once the data sources are connected.
+func setup(configs config.Config) error {
+	// keep the 62golib globals pointed at the default source for helpers that still read them
+	utils.DB = database.Default()
+	utils.DB1, _ = database.Get("1")
+	utils.DB2, _ = database.Get("2")
+
+	for _, name := range database.Names() {
+		db, _ := database.Get(name)
+		if err := metrics.Instrument(name, db); err != nil {
+			return fmt.Errorf("cannot instrument data source %s: %w", name, err)
+		}
+	}
+
+	if err := transformers.Load(configs.SettingPath + "/transformers"); err != nil {
+		return fmt.Errorf("cannot load transformers: %w", err)
+	}
 
 	utils.InitPluralize()
 
-	r := gin.Default()
+	if err := catalogs.Load(configs.SettingPath + "/catalogs.json"); err != nil {
+		return fmt.Errorf("cannot load catalogs: %w", err)
+	}
+
+	return nil
+}
+
+// newRouter builds the gin engine serving the api, the admin endpoints, the docs and the probes.
+func newRouter(configs config.Config) (*gin.Engine, error) {
+	authenticators, err := auth.FromConfig(configs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot setup authentication: %w", err)
+	}
+
+	guards := []gin.HandlerFunc{}
+	if len(authenticators) > 0 {
+		guards = append(guards, middlewares.AuthMiddleware(authenticators))
+	} else {
+		slog.Warn("authentication is disabled and the admin endpoints aren't served, set AUTH_JWT_SECRET, AUTH_JWKS_FILE or AUTH_API_KEY_TABLE to enable it")
+	}
+
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware(), middlewares.MetricsMiddleware(), middlewares.RecoveryMiddleware())
 
-	apiV1 := r.Group("/api/v1").Use(middlewares.DbSelectorMiddleware())
+	apiV1 := r.Group("/api/v1").Use(append(guards, middlewares.DbSelectorMiddleware())...)
 	{
 		RegisterRoute(apiV1, "comment", controllers.CommentController{})
 		RegisterRoute(apiV1, "category", controllers.CategoryController{})
@@ -38,24 +144,96 @@ func main() {
 		RegisterRoute(apiV1, "review", controllers.ReviewController{})
 	}
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, utils.ResponseData("success", "Server running well", nil))
-	})
+	// admin endpoints are reserved to admins, they don't exist without authentication
+	if len(authenticators) > 0 {
+		admin := r.Group("/admin").Use(append(guards, middlewares.AdminMiddleware())...)
+		{
+			admin.POST("/transformers/reload", controllers.TransformerController{}.Reload)
+		}
+	}
 
-	err = r.Run(configs.HTTPServerAddress)
+	docs := controllers.DocsController{Routes: r.Routes}
+	r.GET("/api/docs", docs.UI)
+	r.GET("/api/docs/openapi.json", docs.Spec)
+	r.GET("/api/docs/assets/:file", docs.Asset)
 
-	if err != nil {
-		fmt.Printf("cannot run server: %w", err)
-		return
+	r.GET("/metrics", metrics.Handler())
+
+	health := controllers.HealthController{}
+	r.GET("/health", health.Live)
+	r.GET("/health/live", health.Live)
+	r.GET("/health/ready", health.Ready)
+	r.GET("/version", health.Version)
+
+	return r, nil
+}
+
+// serve runs the server until SIGINT or SIGTERM, then stops accepting connections
+// and waits up to grace for the in-flight requests to complete.
+func serve(server *http.Server, grace time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	slog.Info("server listening", "address", server.Addr)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	// a second signal kills the process right away
+	stop()
+
+	slog.Info("shutting down", "grace_period", grace.String())
+
+	shutdown, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	return server.Shutdown(shutdown)
+}
+
+// runCommand dispatches cli subcommands, eg: ./whale lint
+func runCommand(name string, args []string) int {
+	switch name {
+	case "lint":
+		return commands.Lint(args)
+	case "migrate":
+		return commands.Migrate(args)
+	case "apikey":
+		return commands.APIKey(args)
 	}
+
+	fmt.Println("unknown command " + name)
+
+	return 1
 }
 
 func RegisterRoute(r gin.IRoutes, t string, c interfaces.Crud) {
-	r.GET("/"+t+"/:table/:id", c.Find)
-	r.GET("/"+t+"/:table/slug/:slug", c.Find)
-	r.GET("/"+t+"/:table", c.FindAll)
-	r.POST("/"+t+"/:table", c.Create)
-	r.PUT("/"+t+"/:table/:id", c.Update)
-	r.DELETE("/"+t+"/:table/:id", c.Delete)
-	r.DELETE("/"+t+"/:table", c.DeleteByQuery)
+	guard := func(operation string) gin.HandlerFunc {
+		return middlewares.CatalogMiddleware(t, operation)
+	}
+	allow := func(operation string) gin.HandlerFunc {
+		return middlewares.PolicyMiddleware(t, operation)
+	}
+
+	r.GET("/"+t+"/:table/:id", guard("find"), allow("find"), c.Find)
+	r.GET("/"+t+"/:table/slug/:slug", guard("find"), allow("find"), c.Find)
+	r.GET("/"+t+"/:table", guard("find_all"), allow("find_all"), c.FindAll)
+	r.POST("/"+t+"/:table", guard("create"), allow("create"), c.Create)
+	r.PUT("/"+t+"/:table/:id", guard("update"), allow("update"), c.Update)
+	r.PATCH("/"+t+"/:table/:id", guard("patch"), allow("patch"), c.Patch)
+	r.DELETE("/"+t+"/:table/:id", guard("delete"), allow("delete"), c.Delete)
+	r.DELETE("/"+t+"/:table", guard("delete_by_query"), allow("delete_by_query"), c.DeleteByQuery)
+	r.POST("/"+t+"/:table/:id/restore", guard("restore"), allow("restore"), c.Restore)
+	r.DELETE("/"+t+"/:table/:id/force", guard("force_delete"), allow("force_delete"), c.ForceDelete)
+
+	if b, ok := c.(interfaces.BulkCreator); ok {
+		r.POST("/"+t+"/:table/bulk", guard("bulk_create"), allow("bulk_create"), b.BulkCreate)
+	}
 }
@@ -0,0 +1,1399 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/62teknologi/62whale/app/auth"
+	"github.com/62teknologi/62whale/app/database"
+	"github.com/62teknologi/62whale/app/logger"
+	"github.com/62teknologi/62whale/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// router serves the api on an in-memory sqlite database, seeded with testdata/schema.sql
+// and the fixture transformers of testdata/setting.
+var router *gin.Engine
+
+// secured serves the same api behind the JWT and api key authentication.
+var secured *gin.Engine
+
+const jwtSecret = "test secret"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	configs := config.Config{
+		DBDriver:        "sqlite",
+		DBSources:       map[string]string{"1": ":memory:"},
+		DBDefaultSource: "1",
+		SettingPath:     "testdata/setting",
+		AuthAdminRole:   "admin",
+		LogLevel:        "error",
+		LogFormat:       "text",
+	}
+	config.Data = configs
+
+	if err := start(configs); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	authenticated := configs
+	authenticated.AuthJWTSecret = jwtSecret
+	authenticated.AuthAPIKeyTable = "api_keys"
+
+	var err error
+	if secured, err = newRouter(authenticated); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	database.Close()
+	os.Exit(code)
+}
+
+func start(configs config.Config) error {
+	if err := logger.Setup(configs); err != nil {
+		return err
+	}
+
+	if err := database.Connect(configs.DBDriver, configs.DBSources, configs.DBDefaultSource); err != nil {
+		return err
+	}
+
+	schema, err := os.ReadFile("testdata/schema.sql")
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range strings.Split(string(schema), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		if err := database.Default().Exec(statement).Error; err != nil {
+			return fmt.Errorf("cannot create schema: %w", err)
+		}
+	}
+
+	if err := setup(configs); err != nil {
+		return err
+	}
+
+	router, err = newRouter(configs)
+
+	return err
+}
+
+// call sends a JSON request to the router and decodes the response envelope.
+func call(t *testing.T, method string, path string, body any) (int, map[string]any) {
+	t.Helper()
+
+	code, response, _ := send(t, method, path, body, nil)
+
+	return code, response
+}
+
+// send is call with request headers, it returns the response headers as well.
+func send(t *testing.T, method string, path string, body any, header http.Header) (int, map[string]any, http.Header) {
+	t.Helper()
+
+	return sendTo(t, router, method, path, body, header)
+}
+
+// sendTo is send to another engine, eg: secured.
+func sendTo(t *testing.T, engine *gin.Engine, method string, path string, body any, header http.Header) (int, map[string]any, http.Header) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+
+	response := map[string]any{}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s %s: invalid response %q", method, path, rec.Body.String())
+		}
+	}
+
+	return rec.Code, response, rec.Header()
+}
+
+// expect fails the test when the request wasn't answered with status.
+func expect(t *testing.T, status int, method string, path string, body any) map[string]any {
+	t.Helper()
+
+	code, response := call(t, method, path, body)
+	if code != status {
+		t.Fatalf("%s %s: expected %d, got %d %v", method, path, status, code, response)
+	}
+
+	return response
+}
+
+// insert writes a row straight into the database and returns its id.
+func insert(t *testing.T, table string, row map[string]any) string {
+	t.Helper()
+
+	if err := database.Default().Table(table).Create(row).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var id int64
+	if err := database.Default().Table(table).Select("MAX(id)").Scan(&id).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return strconv.FormatInt(id, 10)
+}
+
+// idOf returns the id of the last row of table having column = value.
+func idOf(t *testing.T, table string, column string, value any) string {
+	t.Helper()
+
+	var id int64
+	if err := database.Default().Table(table).Select("MAX(id)").Where(column+" = ?", value).Scan(&id).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if id == 0 {
+		t.Fatalf("no %s having %s = %v", table, column, value)
+	}
+
+	return strconv.FormatInt(id, 10)
+}
+
+func count(t *testing.T, table string, where string, args ...any) int64 {
+	t.Helper()
+
+	var total int64
+	if err := database.Default().Table(table).Where(where, args...).Count(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return total
+}
+
+func column(t *testing.T, table string, id string, name string) any {
+	t.Helper()
+
+	row := map[string]any{}
+	if err := database.Default().Table(table).Where("id = ?", id).Take(&row).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return row[name]
+}
+
+func data(response map[string]any) map[string]any {
+	value, _ := response["data"].(map[string]any)
+	return value
+}
+
+func list(response map[string]any) []map[string]any {
+	values, _ := response["data"].([]any)
+	rows := []map[string]any{}
+
+	for _, value := range values {
+		if row, ok := value.(map[string]any); ok {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func children(value map[string]any, key string) []map[string]any {
+	return list(map[string]any{"data": value[key]})
+}
+
+func idString(value any) string {
+	return fmt.Sprint(value)
+}
+
+// TestCrud runs every interfaces.Crud operation through the six controllers.
+func TestCrud(t *testing.T) {
+	cases := []struct {
+		kind   string
+		table  string
+		column string
+		create map[string]any
+	}{
+		{
+			kind:   "catalog",
+			table:  "products",
+			column: "name",
+			create: map[string]any{
+				"name":        "crud product",
+				"description": "a product",
+				"user_id":     "1",
+				"items":       []any{map[string]any{"name": "crud product item", "price": "10"}},
+			},
+		},
+		{
+			kind:   "item",
+			table:  "product_items",
+			column: "name",
+			create: map[string]any{"name": "crud item", "product_id": "1", "price": "5"},
+		},
+		{
+			kind:   "group",
+			table:  "product_groups",
+			column: "name",
+			create: map[string]any{"name": "crud group"},
+		},
+		{
+			kind:   "category",
+			table:  "product_categories",
+			column: "name",
+			create: map[string]any{"name": "crud category"},
+		},
+		{
+			kind:   "comment",
+			table:  "product_comments",
+			column: "description",
+			create: map[string]any{"description": "crud comment", "user_id": "1"},
+		},
+		{
+			kind:   "review",
+			table:  "product_reviews",
+			column: "description",
+			create: map[string]any{"description": "crud review", "rating": "4", "user_id": "1"},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.kind, func(t *testing.T) {
+			base := "/api/v1/" + c.kind + "/products"
+			created := c.create[c.column].(string)
+
+			expect(t, http.StatusOK, http.MethodPost, base, c.create)
+			id := idOf(t, c.table, c.column, created)
+
+			found := data(expect(t, http.StatusOK, http.MethodGet, base+"/"+id, nil))
+			if found[c.column] != created {
+				t.Fatalf("find: expected %s %q, got %v", c.column, created, found[c.column])
+			}
+
+			rows := list(expect(t, http.StatusOK, http.MethodGet, base+"?per_page=100", nil))
+			if !containsID(rows, id) {
+				t.Fatalf("find all: %s %s missing from %v", c.table, id, rows)
+			}
+
+			patched := created + " patched"
+			expect(t, http.StatusOK, http.MethodPatch, base+"/"+id, map[string]any{c.column: patched})
+			if value := column(t, c.table, id, c.column); value != patched {
+				t.Fatalf("patch: expected %q, got %v", patched, value)
+			}
+
+			replaced := map[string]any{}
+			for key, value := range c.create {
+				replaced[key] = value
+			}
+			replaced[c.column] = created + " replaced"
+
+			expect(t, http.StatusOK, http.MethodPut, base+"/"+id, replaced)
+			if value := column(t, c.table, id, c.column); value != replaced[c.column] {
+				t.Fatalf("update: expected %q, got %v", replaced[c.column], value)
+			}
+
+			expect(t, http.StatusOK, http.MethodDelete, base+"/"+id, nil)
+			if count(t, c.table, "id = ? AND deleted_at IS NOT NULL", id) != 1 {
+				t.Fatalf("delete: %s %s isn't soft deleted", c.table, id)
+			}
+
+			if code, _ := call(t, http.MethodGet, base+"/"+id, nil); code == http.StatusOK {
+				t.Fatalf("delete: %s %s is still found", c.table, id)
+			}
+
+			expect(t, http.StatusOK, http.MethodPost, base+"/"+id+"/restore", nil)
+			if count(t, c.table, "id = ? AND deleted_at IS NULL", id) != 1 {
+				t.Fatalf("restore: %s %s is still deleted", c.table, id)
+			}
+
+			expect(t, http.StatusOK, http.MethodDelete, base+"/"+id+"/force", nil)
+			if count(t, c.table, "id = ?", id) != 0 {
+				t.Fatalf("force delete: %s %s still exists", c.table, id)
+			}
+
+			expect(t, http.StatusNotFound, http.MethodPatch, base+"/"+id, map[string]any{c.column: patched})
+			expect(t, http.StatusNotFound, http.MethodPut, base+"/"+id, replaced)
+
+			target := created + " by query"
+			id = insert(t, c.table, map[string]any{c.column: target})
+
+			kept := insert(t, c.table, map[string]any{c.column: created + " kept"})
+			expect(t, http.StatusBadRequest, http.MethodDelete, base, nil)
+			expect(t, http.StatusBadRequest, http.MethodDelete, base+"?unknown=1", nil)
+			if count(t, c.table, "id = ? AND deleted_at IS NULL", kept) != 1 {
+				t.Fatalf("delete by query: an unfiltered delete removed %s %s", c.table, kept)
+			}
+
+			expect(t, http.StatusOK, http.MethodDelete, base+"?"+c.column+"="+url.QueryEscape(target), nil)
+			if count(t, c.table, "id = ? AND deleted_at IS NULL", id) != 0 {
+				t.Fatalf("delete by query: %s %s isn't deleted", c.table, id)
+			}
+		})
+	}
+}
+
+func TestCatalogRelationErrors(t *testing.T) {
+	id := insert(t, "products", map[string]any{"name": "relation error"})
+
+	// a relation table that can't be read fails the request instead of answering without its rows
+	if err := database.Default().Exec("ALTER TABLE product_items RENAME TO product_items_away").Error; err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := database.Default().Exec("ALTER TABLE product_items_away RENAME TO product_items").Error; err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	expect(t, http.StatusInternalServerError, http.MethodGet, "/api/v1/catalog/products/"+id, nil)
+	expect(t, http.StatusInternalServerError, http.MethodGet, "/api/v1/catalog/products?id="+id, nil)
+}
+
+func containsID(rows []map[string]any, id string) bool {
+	for _, row := range rows {
+		if idString(row["id"]) == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCatalogRelations(t *testing.T) {
+	small := insert(t, "product_groups", map[string]any{"name": "relation small"})
+	large := insert(t, "product_groups", map[string]any{"name": "relation large"})
+
+	tests := []struct {
+		name       string
+		body       map[string]any
+		items      int64
+		attributes int64
+		groups     int64
+		// price is copied from the default item by "duplicate"
+		price string
+	}{
+		{
+			name: "nested has_many",
+			body: map[string]any{
+				"name": "relation nested",
+				"items": []any{
+					map[string]any{"name": "nested s", "price": "1", "attributes": []any{
+						map[string]any{"type": "size", "value": "s"},
+					}},
+					map[string]any{"name": "nested l", "price": "2", "attributes": []any{
+						map[string]any{"type": "size", "value": "l"},
+						map[string]any{"type": "color", "value": "blue"},
+					}},
+				},
+			},
+			items:      2,
+			attributes: 3,
+		},
+		{
+			name: "many_to_many",
+			body: map[string]any{
+				"name":   "relation groups",
+				"items":  []any{map[string]any{"name": "groups item", "price": "1"}},
+				"groups": []any{small, large},
+			},
+			items:  1,
+			groups: 2,
+		},
+		{
+			name: "duplicate items",
+			body: map[string]any{
+				"name": "relation duplicate",
+				"items": []any{
+					map[string]any{"name": "duplicate first", "price": "1"},
+					map[string]any{"name": "duplicate default", "price": "2", "default": true},
+				},
+			},
+			items: 2,
+			price: "2",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			expect(t, http.StatusOK, http.MethodPost, "/api/v1/catalog/products", test.body)
+			id := idOf(t, "products", "name", test.body["name"])
+
+			if total := count(t, "product_items", "product_id = ?", id); total != test.items {
+				t.Fatalf("expected %d items, got %d", test.items, total)
+			}
+
+			attributes := count(t, "product_item_attributes", "item_id IN (?)", database.Default().Table("product_items").Select("id").Where("product_id = ?", id))
+			if attributes != test.attributes {
+				t.Fatalf("expected %d attributes, got %d", test.attributes, attributes)
+			}
+
+			if total := count(t, "product_group_members", "product_id = ?", id); total != test.groups {
+				t.Fatalf("expected %d groups, got %d", test.groups, total)
+			}
+
+			if price := fmt.Sprint(column(t, "products", id, "price")); test.price != "" && price != test.price {
+				t.Fatalf("expected the price %s of the default item, got %s", test.price, price)
+			}
+
+			found := data(expect(t, http.StatusOK, http.MethodGet, "/api/v1/catalog/products/"+id, nil))
+			if total := len(children(found, "items")); int64(total) != test.items {
+				t.Fatalf("find: expected %d items, got %d", test.items, total)
+			}
+
+			if total := len(children(found, "groups")); int64(total) != test.groups {
+				t.Fatalf("find: expected %d groups, got %d", test.groups, total)
+			}
+		})
+	}
+
+	t.Run("rewrite relations", func(t *testing.T) {
+		id := idOf(t, "products", "name", "relation groups")
+
+		expect(t, http.StatusOK, http.MethodPatch, "/api/v1/catalog/products/"+id, map[string]any{"groups": []any{small}})
+		if total := count(t, "product_group_members", "product_id = ?", id); total != 1 {
+			t.Fatalf("patch: expected 1 group, got %d", total)
+		}
+
+		if total := count(t, "product_items", "product_id = ?", id); total != 1 {
+			t.Fatalf("patch: items not sent must be kept, got %d", total)
+		}
+
+		expect(t, http.StatusOK, http.MethodPut, "/api/v1/catalog/products/"+id, map[string]any{"name": "relation groups"})
+		if total := count(t, "product_group_members", "product_id = ?", id); total != 0 {
+			t.Fatalf("update: expected no group, got %d", total)
+		}
+
+		if total := count(t, "product_items", "product_id = ?", id); total != 0 {
+			t.Fatalf("update: expected no item, got %d", total)
+		}
+	})
+
+	t.Run("missing record", func(t *testing.T) {
+		missing := "999999"
+
+		expect(t, http.StatusNotFound, http.MethodPut, "/api/v1/catalog/products/"+missing, map[string]any{
+			"name":   "relation missing",
+			"items":  []any{map[string]any{"name": "missing item", "price": "1"}},
+			"groups": []any{small},
+		})
+		expect(t, http.StatusNotFound, http.MethodPatch, "/api/v1/catalog/products/"+missing, map[string]any{"groups": []any{large}})
+
+		if total := count(t, "product_items", "product_id = ?", missing); total != 0 {
+			t.Fatalf("expected no item written for a missing product, got %d", total)
+		}
+
+		if total := count(t, "product_group_members", "product_id = ?", missing); total != 0 {
+			t.Fatalf("expected no group written for a missing product, got %d", total)
+		}
+	})
+}
+
+func TestETag(t *testing.T) {
+	cases := []struct {
+		kind  string
+		table string
+		// products have a version column, product_groups only updated_at
+		prefix string
+	}{
+		{kind: "catalog", table: "products", prefix: `"v`},
+		{kind: "group", table: "product_groups", prefix: `"t`},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.kind, func(t *testing.T) {
+			id := insert(t, c.table, map[string]any{"name": "etag " + c.kind})
+			path := "/api/v1/" + c.kind + "/products/" + id
+
+			etag := etagOf(t, path)
+			if !strings.HasPrefix(etag, c.prefix) {
+				t.Fatalf("expected an ETag starting with %s, got %s", c.prefix, etag)
+			}
+
+			if sparse := etagOf(t, path+"?fields=name"); sparse != etag {
+				t.Fatalf("fields must not change the ETag, expected %s, got %s", etag, sparse)
+			}
+
+			stale := http.Header{"If-Match": {`"stale"`}}
+			expectSend(t, http.StatusPreconditionFailed, http.MethodPatch, path, map[string]any{"name": "etag stale"}, stale)
+			if name := column(t, c.table, id, "name"); name != "etag "+c.kind {
+				t.Fatalf("a refused write must not change the record, got %v", name)
+			}
+
+			_, header := expectSend(t, http.StatusOK, http.MethodPatch, path, map[string]any{"name": "etag matching"}, http.Header{"If-Match": {etag}})
+			if next := header.Get("ETag"); next == "" || next != etagOf(t, path) || next == etag {
+				t.Fatalf("expected a new ETag, got %q after %s", next, etag)
+			}
+
+			expectSend(t, http.StatusPreconditionFailed, http.MethodPut, path, map[string]any{"name": "etag replaced"}, http.Header{"If-Match": {etag}})
+			expectSend(t, http.StatusOK, http.MethodPatch, path, map[string]any{"name": "etag any"}, http.Header{"If-Match": {"*"}})
+
+			current := etagOf(t, path)
+			expectSend(t, http.StatusPreconditionFailed, http.MethodDelete, path, nil, stale)
+			expectSend(t, http.StatusOK, http.MethodDelete, path, nil, http.Header{"If-Match": {current}})
+
+			// deleting is a new revision, the ETag read before can't restore the record
+			expectSend(t, http.StatusPreconditionFailed, http.MethodPost, path+"/restore", nil, http.Header{"If-Match": {current}})
+			_, header = expectSend(t, http.StatusOK, http.MethodPost, path+"/restore", nil, http.Header{"If-Match": {"*"}})
+			if restored := header.Get("ETag"); restored != etagOf(t, path) {
+				t.Fatalf("restore: expected the ETag of the restored record, got %q", restored)
+			}
+
+			expectSend(t, http.StatusPreconditionFailed, http.MethodDelete, path+"/force", nil, http.Header{"If-Match": {current}})
+			if count(t, c.table, "id = ?", id) != 1 {
+				t.Fatalf("a refused force delete must keep %s %s", c.table, id)
+			}
+
+			expectSend(t, http.StatusOK, http.MethodDelete, path+"/force", nil, http.Header{"If-Match": {etagOf(t, path)}})
+			if count(t, c.table, "id = ?", id) != 0 {
+				t.Fatalf("force delete: %s %s still exists", c.table, id)
+			}
+		})
+	}
+}
+
+// expectSend is expect with request headers.
+func expectSend(t *testing.T, status int, method string, path string, body any, header http.Header) (map[string]any, http.Header) {
+	t.Helper()
+
+	code, response, responseHeader := send(t, method, path, body, header)
+	if code != status {
+		t.Fatalf("%s %s: expected %d, got %d %v", method, path, status, code, response)
+	}
+
+	return response, responseHeader
+}
+
+// etagOf returns the ETag answered by a find.
+func etagOf(t *testing.T, path string) string {
+	t.Helper()
+
+	_, header := expectSend(t, http.StatusOK, http.MethodGet, path, nil, nil)
+	if header.Get("ETag") == "" {
+		t.Fatalf("GET %s: expected an ETag", path)
+	}
+
+	return header.Get("ETag")
+}
+
+func TestFields(t *testing.T) {
+	body := map[string]any{
+		"name":        "fields product",
+		"description": "a long description",
+		"items":       []any{map[string]any{"name": "fields item", "price": "7"}},
+	}
+
+	expect(t, http.StatusOK, http.MethodPost, "/api/v1/catalog/products", body)
+	id := idOf(t, "products", "name", "fields product")
+
+	found := data(expect(t, http.StatusOK, http.MethodGet, "/api/v1/catalog/products/"+id+"?fields=name,items.price", nil))
+	if found["name"] != "fields product" || idString(found["id"]) != id {
+		t.Fatalf("expected the id and name, got %v", found)
+	}
+
+	if _, ok := found["description"]; ok {
+		t.Fatalf("description wasn't requested, got %v", found)
+	}
+
+	if _, ok := found["groups"]; ok {
+		t.Fatalf("groups weren't requested, got %v", found)
+	}
+
+	items := children(found, "items")
+	if len(items) != 1 || idString(items[0]["price"]) != "7" || items[0]["name"] != nil {
+		t.Fatalf("expected the price of the item only, got %v", items)
+	}
+
+	rows := list(expect(t, http.StatusOK, http.MethodGet, "/api/v1/catalog/products?fields=name&name="+url.QueryEscape("fields product"), nil))
+	if len(rows) != 1 || rows[0]["description"] != nil || rows[0]["name"] != "fields product" {
+		t.Fatalf("expected the name only, got %v", rows)
+	}
+
+	expect(t, http.StatusBadRequest, http.MethodGet, "/api/v1/catalog/products/"+id+"?fields=name,secret", nil)
+	expect(t, http.StatusBadRequest, http.MethodGet, "/api/v1/catalog/products?fields=items.secret", nil)
+}
+
+func TestIncludes(t *testing.T) {
+	group := insert(t, "product_groups", map[string]any{"name": "includes group"})
+	body := map[string]any{
+		"name":   "includes product",
+		"items":  []any{map[string]any{"name": "includes item", "attributes": []any{map[string]any{"type": "size", "value": "m"}}}},
+		"groups": []any{group},
+	}
+
+	expect(t, http.StatusOK, http.MethodPost, "/api/v1/catalog/products", body)
+	base := "/api/v1/catalog/products/" + idOf(t, "products", "name", "includes product")
+
+	tests := []struct {
+		query      string
+		items      bool
+		attributes bool
+		groups     bool
+	}{
+		{query: "", items: true, attributes: true, groups: true},
+		{query: "include=items", items: true},
+		{query: "include=items.attributes", items: true, attributes: true},
+		{query: "include=groups", groups: true},
+		{query: "exclude=items.attributes", items: true, groups: true},
+		{query: "exclude=items,groups"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.query, func(t *testing.T) {
+			found := data(expect(t, http.StatusOK, http.MethodGet, base+"?"+test.query, nil))
+
+			if _, ok := found["groups"]; ok != test.groups {
+				t.Fatalf("expected groups %v, got %v", test.groups, found)
+			}
+
+			items, ok := found["items"]
+			if ok != test.items {
+				t.Fatalf("expected items %v, got %v", test.items, found)
+			}
+
+			if !test.items {
+				return
+			}
+
+			loaded := children(map[string]any{"items": items}, "items")
+			if len(loaded) != 1 {
+				t.Fatalf("expected 1 item, got %v", items)
+			}
+
+			if _, ok := loaded[0]["attributes"]; ok != test.attributes {
+				t.Fatalf("expected attributes %v, got %v", test.attributes, loaded[0])
+			}
+
+			if test.attributes && len(children(loaded[0], "attributes")) != 1 {
+				t.Fatalf("expected 1 attribute, got %v", loaded[0]["attributes"])
+			}
+		})
+	}
+
+	expect(t, http.StatusBadRequest, http.MethodGet, base+"?include=secrets", nil)
+	expect(t, http.StatusBadRequest, http.MethodGet, base+"?include=groups.members", nil)
+}
+
+func TestRelatedQuery(t *testing.T) {
+	shoes := insert(t, "product_categories", map[string]any{"name": "Shoes", "slug": "related-shoes"})
+	hats := insert(t, "product_categories", map[string]any{"name": "Hats", "slug": "related-hats"})
+	group := insert(t, "product_groups", map[string]any{"name": "related group"})
+
+	first := insert(t, "products", map[string]any{"name": "related first", "product_category_id": shoes})
+	second := insert(t, "products", map[string]any{"name": "related second", "product_category_id": hats})
+	third := insert(t, "products", map[string]any{"name": "related third", "product_category_id": shoes})
+
+	if err := database.Default().Table("product_group_members").Create(map[string]any{"product_id": first, "group_id": group}).Error; err != nil {
+		t.Fatal(err)
+	}
+	insert(t, "product_items", map[string]any{"product_id": second, "name": "related item", "price": 100})
+
+	base := "/api/v1/catalog/products?per_page=100&name[like]=related+"
+
+	tests := []struct {
+		name  string
+		query string
+		ids   []string
+	}{
+		{name: "belongs_to filter", query: "category.slug=related-shoes", ids: []string{first, third}},
+		{name: "many_to_many filter", query: "groups.id[]=" + group, ids: []string{first}},
+		{name: "has_many filter", query: "items.price[gte]=50", ids: []string{second}},
+		{name: "belongs_to order", query: "order[]=" + url.QueryEscape("category.name asc") + "&order[]=" + url.QueryEscape("id desc"), ids: []string{second, third, first}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			rows := list(expect(t, http.StatusOK, http.MethodGet, base+"&"+test.query, nil))
+
+			ids := []string{}
+			for _, row := range rows {
+				ids = append(ids, idString(row["id"]))
+			}
+
+			if strings.Join(ids, ",") != strings.Join(test.ids, ",") {
+				t.Fatalf("expected products %v, got %v", test.ids, ids)
+			}
+		})
+	}
+
+	expect(t, http.StatusBadRequest, http.MethodGet, base+"&order=category.secret", nil)
+	expect(t, http.StatusBadRequest, http.MethodGet, base+"&order=items.price", nil)
+}
+
+func TestCatalogBulkCreate(t *testing.T) {
+	body := []any{
+		map[string]any{"name": "bulk first", "items": []any{map[string]any{"name": "bulk first item"}}},
+		map[string]any{"name": "bulk second", "items": []any{map[string]any{"name": "bulk second item"}}},
+	}
+
+	expect(t, http.StatusOK, http.MethodPost, "/api/v1/catalog/products/bulk", body)
+
+	if total := count(t, "products", "name IN ?", []string{"bulk first", "bulk second"}); total != 2 {
+		t.Fatalf("expected 2 products, got %d", total)
+	}
+
+	invalid := []any{
+		map[string]any{"name": "bulk valid"},
+		map[string]any{"description": "no name"},
+	}
+
+	expect(t, http.StatusBadRequest, http.MethodPost, "/api/v1/catalog/products/bulk", invalid)
+
+	if total := count(t, "products", "name = ?", "bulk valid"); total != 0 {
+		t.Fatalf("atomic bulk must not create any product, got %d", total)
+	}
+
+	partial := []any{
+		map[string]any{"name": "bulk partial valid", "items": []any{map[string]any{"name": "bulk partial item"}}},
+		map[string]any{"description": "no name"},
+		// passes validation, the null group id is refused by the database after the product is written
+		map[string]any{"name": "bulk partial failed", "items": []any{map[string]any{"name": "bulk failed item"}}, "groups": []any{nil}},
+	}
+
+	response := expect(t, http.StatusMultiStatus, http.MethodPost, "/api/v1/catalog/products/bulk?mode=partial", partial)
+
+	results, _ := response["data"].([]any)
+	for i, status := range []string{"created", "invalid", "failed"} {
+		if result, _ := results[i].(map[string]any); result["status"] != status {
+			t.Fatalf("partial bulk: expected record %d %s, got %v", i, status, results[i])
+		}
+	}
+
+	if total := count(t, "products", "name = ?", "bulk partial valid"); total != 1 {
+		t.Fatalf("partial bulk: expected the valid product, got %d", total)
+	}
+
+	if total := count(t, "products", "name = ?", "bulk partial failed"); total != 0 {
+		t.Fatalf("partial bulk: the failed product must be rolled back, got %d", total)
+	}
+
+	if total := count(t, "product_items", "name IN ?", []string{"bulk partial item", "bulk failed item"}); total != 1 {
+		t.Fatalf("partial bulk: expected only the item of the valid product, got %d", total)
+	}
+}
+
+func TestFindAllQuery(t *testing.T) {
+	ids := []string{}
+	for i := 1; i <= 5; i++ {
+		ids = append(ids, insert(t, "product_groups", map[string]any{"name": "query group " + strconv.Itoa(i)}))
+	}
+	trashed := insert(t, "product_groups", map[string]any{"name": "query trashed", "deleted_at": "2023-01-01 00:00:00"})
+
+	base := "/api/v1/group/products"
+
+	tests := []struct {
+		name  string
+		query string
+		check func(t *testing.T, rows []map[string]any)
+	}{
+		{
+			name:  "filter",
+			query: "name=" + url.QueryEscape("query group 3"),
+			check: func(t *testing.T, rows []map[string]any) {
+				if len(rows) != 1 || idString(rows[0]["id"]) != ids[2] {
+					t.Fatalf("expected group %s only, got %v", ids[2], rows)
+				}
+			},
+		},
+		{
+			name:  "multi value filter",
+			query: "id[]=" + ids[0] + "&id[]=" + ids[1],
+			check: func(t *testing.T, rows []map[string]any) {
+				if len(rows) != 2 || !containsID(rows, ids[0]) || !containsID(rows, ids[1]) {
+					t.Fatalf("expected groups %s and %s, got %v", ids[0], ids[1], rows)
+				}
+			},
+		},
+		{
+			name:  "range operators",
+			query: "id[gte]=" + ids[1] + "&id[lt]=" + ids[4],
+			check: func(t *testing.T, rows []map[string]any) {
+				if len(rows) != 3 || containsID(rows, ids[0]) || containsID(rows, ids[4]) {
+					t.Fatalf("expected groups %s to %s, got %v", ids[1], ids[3], rows)
+				}
+			},
+		},
+		{
+			name:  "like operator",
+			query: "name[like]=" + url.QueryEscape("group 4"),
+			check: func(t *testing.T, rows []map[string]any) {
+				if len(rows) != 1 || idString(rows[0]["id"]) != ids[3] {
+					t.Fatalf("expected group %s only, got %v", ids[3], rows)
+				}
+			},
+		},
+		{
+			name:  "search",
+			query: "search=" + url.QueryEscape("query group") + "&per_page=100",
+			check: func(t *testing.T, rows []map[string]any) {
+				for _, id := range ids {
+					if !containsID(rows, id) {
+						t.Fatalf("search: group %s missing from %v", id, rows)
+					}
+				}
+
+				for _, row := range rows {
+					if !strings.Contains(fmt.Sprint(row["name"]), "query group") {
+						t.Fatalf("search: unexpected group %v", row)
+					}
+				}
+			},
+		},
+		{
+			name:  "pagination",
+			query: "search=" + url.QueryEscape("query group") + "&page=2&per_page=2",
+			check: func(t *testing.T, rows []map[string]any) {
+				if len(rows) != 2 {
+					t.Fatalf("expected 2 groups on page 2, got %v", rows)
+				}
+			},
+		},
+		{
+			name:  "order",
+			query: "search=" + url.QueryEscape("query group") + "&order=" + url.QueryEscape("id desc") + "&per_page=1",
+			check: func(t *testing.T, rows []map[string]any) {
+				if len(rows) != 1 || idString(rows[0]["id"]) != ids[4] {
+					t.Fatalf("expected group %s first, got %v", ids[4], rows)
+				}
+			},
+		},
+		{
+			name:  "without trashed",
+			query: "per_page=100",
+			check: func(t *testing.T, rows []map[string]any) {
+				if containsID(rows, trashed) {
+					t.Fatalf("trashed group %s must be hidden", trashed)
+				}
+			},
+		},
+		{
+			name:  "only trashed",
+			query: "only_trashed=true&per_page=100",
+			check: func(t *testing.T, rows []map[string]any) {
+				if !containsID(rows, trashed) || containsID(rows, ids[0]) {
+					t.Fatalf("expected trashed groups only, got %v", rows)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			test.check(t, list(expect(t, http.StatusOK, http.MethodGet, base+"?"+test.query, nil)))
+		})
+	}
+
+	t.Run("operator not allowed", func(t *testing.T) {
+		expect(t, http.StatusBadRequest, http.MethodGet, base+"?name[gte]=a", nil)
+		expect(t, http.StatusBadRequest, http.MethodGet, base+"?id[gt]=one", nil)
+	})
+}
+
+func TestCursorPagination(t *testing.T) {
+	slugs := []any{"cursor-b", nil, "cursor-a", nil, "cursor-c"}
+	ids := []string{}
+	for i, slug := range slugs {
+		ids = append(ids, insert(t, "product_groups", map[string]any{"name": "cursor group " + strconv.Itoa(i), "slug": slug}))
+	}
+
+	base := "/api/v1/group/products?name[like]=" + url.QueryEscape("cursor group")
+
+	first := expect(t, http.StatusOK, http.MethodGet, base+"&cursor=&limit=2", nil)
+	expectPage(t, first, ids[0], ids[1])
+	if pagination(first)["prev_cursor"] != nil {
+		t.Fatalf("the first page has no previous page, got %v", pagination(first))
+	}
+	if _, ok := pagination(first)["total"]; ok {
+		t.Fatalf("total is counted only with with_total, got %v", pagination(first))
+	}
+
+	second := expect(t, http.StatusOK, http.MethodGet, base+"&limit=2&cursor="+cursorOf(t, first, "next_cursor"), nil)
+	expectPage(t, second, ids[2], ids[3])
+
+	last := expect(t, http.StatusOK, http.MethodGet, base+"&limit=2&cursor="+cursorOf(t, second, "next_cursor"), nil)
+	expectPage(t, last, ids[4])
+	if pagination(last)["next_cursor"] != nil {
+		t.Fatalf("the last page has no next page, got %v", pagination(last))
+	}
+
+	back := expect(t, http.StatusOK, http.MethodGet, base+"&limit=2&cursor="+cursorOf(t, last, "prev_cursor"), nil)
+	expectPage(t, back, ids[2], ids[3])
+
+	back = expect(t, http.StatusOK, http.MethodGet, base+"&limit=2&cursor="+cursorOf(t, back, "prev_cursor"), nil)
+	expectPage(t, back, ids[0], ids[1])
+	if pagination(back)["prev_cursor"] != nil {
+		t.Fatalf("going back to the first page must end the previous pages, got %v", pagination(back))
+	}
+
+	counted := expect(t, http.StatusOK, http.MethodGet, base+"&cursor=&limit=2&with_total=true", nil)
+	if total := fmt.Sprint(pagination(counted)["total"]); total != "5" {
+		t.Fatalf("expected a total of 5, got %s", total)
+	}
+
+	t.Run("null order values", func(t *testing.T) {
+		tests := []struct {
+			order string
+			ids   []string
+		}{
+			{order: "slug asc", ids: []string{ids[2], ids[0], ids[4], ids[1], ids[3]}},
+			{order: "slug desc", ids: []string{ids[1], ids[3], ids[4], ids[0], ids[2]}},
+		}
+
+		for _, test := range tests {
+			path := base + "&limit=2&order=" + url.QueryEscape(test.order) + "&cursor="
+			read := []string{}
+
+			for cursor := ""; ; {
+				response := expect(t, http.StatusOK, http.MethodGet, path+cursor, nil)
+				for _, row := range list(response) {
+					read = append(read, idString(row["id"]))
+				}
+
+				next, _ := pagination(response)["next_cursor"].(string)
+				if next == "" {
+					break
+				}
+				cursor = url.QueryEscape(next)
+			}
+
+			if strings.Join(read, ",") != strings.Join(test.ids, ",") {
+				t.Fatalf("%s: expected %v, got %v", test.order, test.ids, read)
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, limit := range []string{"0", "1001", "two"} {
+			expect(t, http.StatusBadRequest, http.MethodGet, base+"&cursor=&limit="+limit, nil)
+		}
+		expect(t, http.StatusOK, http.MethodGet, base+"&cursor=&limit=1000", nil)
+
+		next := cursorOf(t, first, "next_cursor")
+		expect(t, http.StatusBadRequest, http.MethodGet, base+"&limit=2&order="+url.QueryEscape("id desc")+"&cursor="+next, nil)
+		expect(t, http.StatusBadRequest, http.MethodGet, base+"&limit=2&cursor=not-a-cursor", nil)
+		expect(t, http.StatusBadRequest, http.MethodGet, base+"&limit=2&cursor="+next[:len(next)/2], nil)
+	})
+}
+
+func pagination(response map[string]any) map[string]any {
+	value, _ := response["pagination"].(map[string]any)
+	return value
+}
+
+// cursorOf returns the escaped cursor of a neighbour page.
+func cursorOf(t *testing.T, response map[string]any, key string) string {
+	t.Helper()
+
+	cursor, _ := pagination(response)[key].(string)
+	if cursor == "" {
+		t.Fatalf("expected a %s, got %v", key, pagination(response))
+	}
+
+	return url.QueryEscape(cursor)
+}
+
+// expectPage fails the test when the page doesn't hold the ids in order.
+func expectPage(t *testing.T, response map[string]any, ids ...string) {
+	t.Helper()
+
+	read := []string{}
+	for _, row := range list(response) {
+		read = append(read, idString(row["id"]))
+	}
+
+	if strings.Join(read, ",") != strings.Join(ids, ",") {
+		t.Fatalf("expected the page %v, got %v", ids, read)
+	}
+}
+
+func TestRecursiveChilds(t *testing.T) {
+	cases := []struct {
+		kind   string
+		table  string
+		column string
+	}{
+		{kind: "category", table: "product_categories", column: "name"},
+		{kind: "comment", table: "product_comments", column: "description"},
+	}
+
+	for _, c := range cases {
+		c := c
+
+		t.Run(c.kind, func(t *testing.T) {
+			root := insert(t, c.table, map[string]any{c.column: c.kind + " root"})
+			child := insert(t, c.table, map[string]any{c.column: c.kind + " child", "parent_id": root})
+			insert(t, c.table, map[string]any{c.column: c.kind + " grandchild", "parent_id": child})
+
+			base := "/api/v1/" + c.kind + "/products"
+
+			found := data(expect(t, http.StatusOK, http.MethodGet, base+"/"+root, nil))
+			childs := children(found, "childs")
+			if len(childs) != 1 || idString(childs[0]["id"]) != child {
+				t.Fatalf("expected child %s, got %v", child, childs)
+			}
+
+			grandchilds := children(childs[0], "childs")
+			if len(grandchilds) != 1 || grandchilds[0][c.column] != c.kind+" grandchild" {
+				t.Fatalf("expected the grandchild, got %v", grandchilds)
+			}
+
+			found = data(expect(t, http.StatusOK, http.MethodGet, base+"/"+root+"?depth=1", nil))
+			childs = children(found, "childs")
+			if len(childs) != 1 || len(children(childs[0], "childs")) != 0 {
+				t.Fatalf("depth=1 must stop at the children, got %v", childs)
+			}
+
+			expect(t, http.StatusBadRequest, http.MethodGet, base+"/"+root+"?depth=zero", nil)
+
+			rows := list(expect(t, http.StatusOK, http.MethodGet, base+"?include_childs=true&per_page=100", nil))
+			for _, row := range rows {
+				if idString(row["id"]) == root {
+					if len(children(row, "childs")) != 1 {
+						t.Fatalf("find all: expected the child of %s, got %v", root, row["childs"])
+					}
+					return
+				}
+			}
+
+			t.Fatalf("find all: %s %s missing", c.table, root)
+		})
+	}
+}
+
+func TestAdminWithoutAuthentication(t *testing.T) {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/transformers/reload", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("admin endpoints must not be served without authentication, got %d", rec.Code)
+	}
+}
+
+// signed returns a token of claims signed by method with key.
+func signed(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// bearer authenticates as subject with roles through a valid HS256 token.
+func bearer(t *testing.T, subject string, roles ...string) http.Header {
+	t.Helper()
+
+	claims := jwt.MapClaims{"sub": subject, "roles": roles, "exp": time.Now().Add(time.Hour).Unix()}
+
+	return http.Header{"Authorization": {"Bearer " + signed(t, jwt.SigningMethodHS256, []byte(jwtSecret), claims)}}
+}
+
+// expectAs fails the test when the request sent to secured with the credentials of header wasn't answered with status.
+func expectAs(t *testing.T, header http.Header, status int, method string, path string, body any) map[string]any {
+	t.Helper()
+
+	code, response, _ := sendTo(t, secured, method, path, body, header)
+	if code != status {
+		t.Fatalf("%s %s: expected %d, got %d %v", method, path, status, code, response)
+	}
+
+	return response
+}
+
+func TestAuthentication(t *testing.T) {
+	keys := auth.NewAPIKeys(database.Default(), "api_keys")
+
+	key, err := keys.Create("importer", []string{"importer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := keys.Create("revoked", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.Default().Table("api_keys").Where("key_hash = ?", auth.HashKey(revoked)).Update("deleted_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	hour := time.Now().Add(time.Hour).Unix()
+	token := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) http.Header {
+		return http.Header{"Authorization": {"Bearer " + signed(t, method, key, claims)}}
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{name: "no credentials", status: http.StatusUnauthorized},
+		{name: "jwt", header: bearer(t, "1"), status: http.StatusOK},
+		{name: "expired jwt", header: token(jwt.SigningMethodHS256, []byte(jwtSecret), jwt.MapClaims{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()}), status: http.StatusUnauthorized},
+		{name: "jwt without subject", header: token(jwt.SigningMethodHS256, []byte(jwtSecret), jwt.MapClaims{"exp": hour}), status: http.StatusUnauthorized},
+		{name: "jwt with an empty subject", header: token(jwt.SigningMethodHS256, []byte(jwtSecret), jwt.MapClaims{"sub": "", "exp": hour}), status: http.StatusUnauthorized},
+		{name: "jwt without expiration", header: token(jwt.SigningMethodHS256, []byte(jwtSecret), jwt.MapClaims{"sub": "1"}), status: http.StatusUnauthorized},
+		{name: "jwt signed with another secret", header: token(jwt.SigningMethodHS256, []byte("another secret"), jwt.MapClaims{"sub": "1", "exp": hour}), status: http.StatusUnauthorized},
+		{name: "jwt signed with another algorithm", header: token(jwt.SigningMethodHS384, []byte(jwtSecret), jwt.MapClaims{"sub": "1", "exp": hour}), status: http.StatusUnauthorized},
+		{name: "unsigned jwt", header: token(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{"sub": "1", "exp": hour}), status: http.StatusUnauthorized},
+		{name: "api key", header: http.Header{auth.APIKeyHeader: {key}}, status: http.StatusOK},
+		{name: "unknown api key", header: http.Header{auth.APIKeyHeader: {"unknown"}}, status: http.StatusUnauthorized},
+		{name: "revoked api key", header: http.Header{auth.APIKeyHeader: {revoked}}, status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			expectAs(t, test.header, test.status, http.MethodGet, "/api/v1/catalog/products", nil)
+		})
+	}
+
+	t.Run("api key lookup failure", func(t *testing.T) {
+		broken := config.Data
+		broken.AuthAPIKeyTable = "missing_api_keys"
+
+		engine, err := newRouter(broken)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the key can't be checked, it is neither accepted nor refused and the driver error isn't leaked
+		status, response, _ := sendTo(t, engine, http.MethodGet, "/api/v1/catalog/products", nil, http.Header{auth.APIKeyHeader: {key}})
+		if status != http.StatusInternalServerError || strings.Contains(fmt.Sprint(response), "missing_api_keys") {
+			t.Fatalf("expected 500 without the driver error, got %d %v", status, response)
+		}
+	})
+}
+
+func TestAdminReload(t *testing.T) {
+	expectAs(t, nil, http.StatusUnauthorized, http.MethodPost, "/admin/transformers/reload", nil)
+	expectAs(t, bearer(t, "1", "seller"), http.StatusForbidden, http.MethodPost, "/admin/transformers/reload", nil)
+	expectAs(t, bearer(t, "1", "admin"), http.StatusOK, http.MethodPost, "/admin/transformers/reload", nil)
+}
+
+func TestOwnership(t *testing.T) {
+	base := "/api/v1/catalog/posts"
+	alice, bob, admin := bearer(t, "1", "author"), bearer(t, "2", "author"), bearer(t, "3", "admin")
+
+	expectAs(t, alice, http.StatusOK, http.MethodPost, base, map[string]any{"title": "owned post", "user_id": "2", "tags": []any{map[string]any{"name": "news"}}})
+	id := idOf(t, "posts", "title", "owned post")
+
+	if owner := fmt.Sprint(column(t, "posts", id, "user_id")); owner != "1" {
+		t.Fatalf("create: expected the owner 1 of the token, got %s", owner)
+	}
+
+	t.Run("foreign row", func(t *testing.T) {
+		expectAs(t, bob, http.StatusNotFound, http.MethodPatch, base+"/"+id, map[string]any{"title": "stolen post"})
+		expectAs(t, bob, http.StatusNotFound, http.MethodPut, base+"/"+id, map[string]any{"title": "stolen post"})
+		expectAs(t, bob, http.StatusNotFound, http.MethodDelete, base+"/"+id, nil)
+		expectAs(t, bob, http.StatusNotFound, http.MethodDelete, base+"/"+id+"/force", nil)
+		expectAs(t, bob, http.StatusOK, http.MethodDelete, base+"?title="+url.QueryEscape("owned post"), nil)
+
+		if value := column(t, "posts", id, "title"); value != "owned post" {
+			t.Fatalf("expected the title to be kept, got %v", value)
+		}
+
+		if count(t, "posts", "id = ? AND deleted_at IS NULL", id) != 1 {
+			t.Fatalf("posts %s was deleted by another principal", id)
+		}
+
+		// reads aren't restricted by the owner
+		expectAs(t, bob, http.StatusOK, http.MethodGet, base+"/"+id, nil)
+	})
+
+	t.Run("owner can't be handed over", func(t *testing.T) {
+		expectAs(t, alice, http.StatusOK, http.MethodPatch, base+"/"+id, map[string]any{"title": "owned post", "user_id": "2"})
+		expectAs(t, alice, http.StatusOK, http.MethodPut, base+"/"+id, map[string]any{"title": "owned post", "user_id": "2"})
+
+		if owner := fmt.Sprint(column(t, "posts", id, "user_id")); owner != "1" {
+			t.Fatalf("expected the owner to be kept, got %s", owner)
+		}
+	})
+
+	t.Run("owner", func(t *testing.T) {
+		expectAs(t, alice, http.StatusOK, http.MethodDelete, base+"/"+id, nil)
+		expectAs(t, bob, http.StatusNotFound, http.MethodPost, base+"/"+id+"/restore", nil)
+		expectAs(t, alice, http.StatusOK, http.MethodPost, base+"/"+id+"/restore", nil)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		expectAs(t, admin, http.StatusOK, http.MethodPost, base, map[string]any{"title": "assigned post", "user_id": "2", "tags": []any{map[string]any{"name": "news"}}})
+		assigned := idOf(t, "posts", "title", "assigned post")
+
+		if owner := fmt.Sprint(column(t, "posts", assigned, "user_id")); owner != "2" {
+			t.Fatalf("expected an admin to pick the owner 2, got %s", owner)
+		}
+
+		expectAs(t, admin, http.StatusOK, http.MethodPatch, base+"/"+id, map[string]any{"user_id": "2"})
+		expectAs(t, alice, http.StatusNotFound, http.MethodPatch, base+"/"+id, map[string]any{"title": "handed over post"})
+		expectAs(t, bob, http.StatusOK, http.MethodPatch, base+"/"+id, map[string]any{"title": "handed over post"})
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	base := "/api/v1/catalog/posts"
+	author, moderator, reader := bearer(t, "5", "author"), bearer(t, "6", "author", "moderator"), bearer(t, "7", "reader")
+
+	post := func(title string, fields map[string]any) map[string]any {
+		body := map[string]any{"title": title, "tags": []any{map[string]any{"name": "policy"}}}
+		for key, value := range fields {
+			body[key] = value
+		}
+
+		return body
+	}
+
+	t.Run("operations", func(t *testing.T) {
+		expect(t, http.StatusOK, http.MethodGet, base, nil)
+		expect(t, http.StatusUnauthorized, http.MethodPost, base, post("anonymous post", nil))
+
+		expectAs(t, reader, http.StatusOK, http.MethodGet, base, nil)
+		expectAs(t, reader, http.StatusForbidden, http.MethodPost, base, post("reader post", nil))
+
+		expectAs(t, author, http.StatusOK, http.MethodPost, base, post("author post", nil))
+		id := idOf(t, "posts", "title", "author post")
+
+		expectAs(t, reader, http.StatusForbidden, http.MethodDelete, base+"/"+id, nil)
+		expectAs(t, author, http.StatusOK, http.MethodDelete, base+"/"+id, nil)
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		expectAs(t, author, http.StatusForbidden, http.MethodPost, base, post("author status", map[string]any{"status_id": "1"}))
+		expectAs(t, author, http.StatusForbidden, http.MethodPost, base, post("author weight", map[string]any{
+			"tags": []any{map[string]any{"name": "policy"}, map[string]any{"name": "weighted", "weight": "2"}},
+		}))
+
+		expectAs(t, moderator, http.StatusOK, http.MethodPost, base, post("moderated post", map[string]any{
+			"status_id": "1",
+			"tags":      []any{map[string]any{"name": "weighted", "weight": "2"}},
+		}))
+		id := idOf(t, "posts", "title", "moderated post")
+
+		expectAs(t, author, http.StatusForbidden, http.MethodPatch, base+"/"+id, map[string]any{"status_id": "2"})
+		expectAs(t, author, http.StatusForbidden, http.MethodPut, base+"/"+id, post("moderated post", map[string]any{
+			"tags": []any{map[string]any{"name": "weighted", "weight": "3"}},
+		}))
+		expectAs(t, moderator, http.StatusOK, http.MethodPatch, base+"/"+id, map[string]any{"status_id": "2"})
+		expectAs(t, bearer(t, "8", "admin"), http.StatusOK, http.MethodPatch, base+"/"+id, map[string]any{"status_id": "3"})
+
+		if status := fmt.Sprint(column(t, "posts", id, "status_id")); status != "3" {
+			t.Fatalf("expected the status 3, got %s", status)
+		}
+	})
+
+	t.Run("form fields", func(t *testing.T) {
+		form := url.Values{"title": {"form post"}, "tags[0][name]": {"form"}, "tags[0][weight]": {"2"}}
+
+		req := httptest.NewRequest(http.MethodPost, base, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header["Authorization"] = author["Authorization"]
+
+		rec := httptest.NewRecorder()
+		secured.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected 403 for a restricted field of a form, got %d %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+func TestDocsDeleteByQuery(t *testing.T) {
+	spec := expect(t, http.StatusOK, http.MethodGet, "/api/docs/openapi.json", nil)
+
+	paths, _ := spec["paths"].(map[string]any)
+	path, _ := paths["/api/v1/catalog/products"].(map[string]any)
+	operation, _ := path["delete"].(map[string]any)
+	parameters, _ := operation["parameters"].([]any)
+
+	names := map[string]bool{}
+	for _, parameter := range parameters {
+		name, _ := parameter.(map[string]any)["name"].(string)
+		names[name] = true
+	}
+
+	// filterable by request/products/delete.json, only the response find filters on description and user_id
+	for name, expected := range map[string]bool{"id": true, "name": true, "description": false, "user_id": false} {
+		if names[name] != expected {
+			t.Errorf("delete by query: parameter %s documented %v, expected %v", name, names[name], expected)
+		}
+	}
+}
+
+func TestDocsUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "https://") {
+		t.Fatalf("docs: expected a page loading its assets from the api, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	for path, contentType := range map[string]string{
+		"/api/docs/assets/swagger-ui.css":       "text/css",
+		"/api/docs/assets/swagger-ui-bundle.js": "javascript",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Type"), contentType) || rec.Body.Len() == 0 {
+			t.Fatalf("%s: expected %s, got %d %q", path, contentType, rec.Code, rec.Header().Get("Content-Type"))
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs/assets/missing.js", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("missing asset: expected 404, got %d", rec.Code)
+	}
+}
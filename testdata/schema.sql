@@ -0,0 +1,109 @@
+CREATE TABLE products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NULL,
+    description TEXT NULL,
+    price BIGINT NULL,
+    product_category_id BIGINT NULL,
+    user_id BIGINT NULL,
+    version BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE product_items (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id BIGINT NULL,
+    name VARCHAR(255) NULL,
+    slug VARCHAR(255) NULL,
+    price BIGINT NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE product_item_attributes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id BIGINT NULL,
+    type VARCHAR(255) NULL,
+    value VARCHAR(255) NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE product_groups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE product_group_members (
+    product_id BIGINT NOT NULL,
+    group_id BIGINT NOT NULL
+);
+
+CREATE TABLE product_categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    parent_id BIGINT NULL,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE product_comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    parent_id BIGINT NULL,
+    user_id BIGINT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE product_reviews (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NULL,
+    rating BIGINT NULL,
+    description TEXT NOT NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE posts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NULL,
+    body TEXT NULL,
+    status_id BIGINT NULL,
+    user_id BIGINT NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);
+
+CREATE TABLE post_tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id BIGINT NULL,
+    name VARCHAR(255) NULL,
+    slug VARCHAR(255) NULL,
+    weight BIGINT NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    roles VARCHAR(255) NULL,
+    created_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP NULL
+);